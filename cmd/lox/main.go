@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"lox-server/internal/lox"
+	"os"
+)
+
+const usage = "Usage: lox run <file>"
+
+func main() {
+	if len(os.Args) != 3 || os.Args[1] != "run" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(64)
+	}
+	os.Exit(runFile(os.Args[2]))
+}
+
+// runFile executes a script and returns the exit code, following the conventions of jlox
+func runFile(path string) int {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 66
+	}
+
+	_, ast, compileErrors, _, _, _, err := lox.ParseCode(string(code))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 65
+	}
+	hasErrors := false
+	for _, compileError := range compileErrors {
+		if compileError.Severity != 1 {
+			continue
+		}
+		hasErrors = true
+		fmt.Fprintf(os.Stderr, "[line %d:%d] Error: %s\n", compileError.Line+1, compileError.Char+1, compileError.Message)
+	}
+	if hasErrors {
+		return 65
+	}
+
+	var interpreter lox.Interpreter
+	if err := interpreter.Interpret(ast, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 70
+	}
+	return 0
+}
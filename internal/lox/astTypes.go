@@ -2,6 +2,7 @@ package lox
 
 type Node interface {
 	Accept(Visitor)
+	GetSpan() Span
 }
 
 type Visitor interface {
@@ -15,6 +16,7 @@ type Visitor interface {
 	visitAssignment(*Assignment)
 	visitCall(*Call)
 	visitGetExpr(*GetExpr)
+	visitSetExpr(*SetExpr)
 	visitExprStmt(*ExpressionStmt)
 	visitPrint(*PrintStmt)
 	visitReturn(*ReturnStmt)
@@ -30,6 +32,7 @@ type Visitor interface {
 }
 
 type Comment struct {
+	Span
 	Comment Token
 	Inline  bool
 }
@@ -39,6 +42,7 @@ func (expr *Comment) Accept(visitor Visitor) {
 }
 
 type Primary struct {
+	Span
 	Value   any
 	ValType string
 }
@@ -48,9 +52,11 @@ func (expr *Primary) Accept(visitor Visitor) {
 }
 
 type Binary struct {
+	Span
 	Left      Node
 	Right     Node
 	Operation int
+	Operator  Token
 }
 
 func (expr *Binary) Accept(visitor Visitor) {
@@ -58,8 +64,10 @@ func (expr *Binary) Accept(visitor Visitor) {
 }
 
 type Unary struct {
+	Span
 	Expression Node
 	Operation  int
+	Operator   Token
 }
 
 func (expr *Unary) Accept(visitor Visitor) {
@@ -67,6 +75,7 @@ func (expr *Unary) Accept(visitor Visitor) {
 }
 
 type Group struct {
+	Span
 	Expression Node
 }
 
@@ -75,8 +84,10 @@ func (expr *Group) Accept(visitor Visitor) {
 }
 
 type Variable struct {
+	Span
 	Identifier Token
 	Definition Token
+	Write      bool // target of an assignment rather than a read
 }
 
 func (expr *Variable) Accept(visitor Visitor) {
@@ -84,6 +95,7 @@ func (expr *Variable) Accept(visitor Visitor) {
 }
 
 type This struct {
+	Span
 	Identifier Token
 }
 
@@ -92,8 +104,10 @@ func (expr *This) Accept(visitor Visitor) {
 }
 
 type Super struct {
-	Identifier Token
-	Property   Token
+	Span
+	Identifier  Token
+	Property    Token
+	Definitions []Token // methods the property resolves to
 }
 
 func (expr *Super) Accept(visitor Visitor) {
@@ -101,6 +115,7 @@ func (expr *Super) Accept(visitor Visitor) {
 }
 
 type Assignment struct {
+	Span
 	Value      Node
 	Identifier Node
 }
@@ -110,8 +125,10 @@ func (expr *Assignment) Accept(visitor Visitor) {
 }
 
 type Call struct {
+	Span
 	Callee   Node
 	Argument []Node
+	Paren    Token // closing paren, locates runtime errors
 }
 
 func (expr *Call) Accept(visitor Visitor) {
@@ -119,10 +136,23 @@ func (expr *Call) Accept(visitor Visitor) {
 }
 
 type GetExpr struct {
-	Object   Node
-	Property Token
+	Span
+	Object      Node
+	Property    Token
+	Definitions []Token // methods the property may resolve to, every same-named method when the object is unknown
 }
 
 func (expr *GetExpr) Accept(visitor Visitor) {
 	visitor.visitGetExpr(expr)
 }
+
+type SetExpr struct {
+	Span
+	Object   Node
+	Property Token
+	Value    Node
+}
+
+func (expr *SetExpr) Accept(visitor Visitor) {
+	visitor.visitSetExpr(expr)
+}
@@ -0,0 +1,40 @@
+package lox
+
+type CallSite struct {
+	Caller  *FuncDecl // enclosing function or method, nil for top level code
+	Site    Token     // callee name or property at the call
+	Callees []Token   // definitions the call may reach
+}
+
+// CallSites lists the calls whose callee the resolver could link to a declaration
+func CallSites(ast []Node) []CallSite {
+	sites := make([]CallSite, 0)
+	var collect func(nodes []Node, caller *FuncDecl)
+	collect = func(nodes []Node, caller *FuncDecl) {
+		Inspect(nodes, func(node Node) bool {
+			switch node := node.(type) {
+			case *FuncDecl:
+				collect([]Node{node.Body}, node)
+				return false
+			case *Call:
+				switch callee := node.Callee.(type) {
+				case *Variable:
+					if callee.Definition != (Token{}) {
+						sites = append(sites, CallSite{Caller: caller, Site: callee.Identifier, Callees: []Token{callee.Definition}})
+					}
+				case *GetExpr:
+					if len(callee.Definitions) > 0 {
+						sites = append(sites, CallSite{Caller: caller, Site: callee.Property, Callees: callee.Definitions})
+					}
+				case *Super:
+					if len(callee.Definitions) > 0 {
+						sites = append(sites, CallSite{Caller: caller, Site: callee.Property, Callees: callee.Definitions})
+					}
+				}
+			}
+			return true
+		})
+	}
+	collect(ast, nil)
+	return sites
+}
@@ -0,0 +1,238 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DECLARATION_VARIABLE = iota
+	DECLARATION_FUNCTION
+	DECLARATION_METHOD
+	DECLARATION_CLASS
+	DECLARATION_PARAMETER
+)
+
+type Declaration struct {
+	Name     Token
+	Kind     int
+	Node     Node         // *VarDecl, *FuncDecl, *ClassDecl or the *Variable of a parameter
+	Parent   *Declaration // enclosing function or class, nil for globals
+	Comments []Token      // line comments directly above the declaration
+}
+
+// Declarations lists every declaration in the program in source order
+func Declarations(ast []Node) []*Declaration {
+	var collector declarationCollector
+	collector.declarations = make([]*Declaration, 0)
+	collector.visitBody(ast)
+	return collector.declarations
+}
+
+func (declaration *Declaration) Signature() string {
+	name, _ := declaration.Name.Value.(string)
+	switch declaration.Kind {
+	case DECLARATION_VARIABLE:
+		return fmt.Sprintf("var %s", name)
+	case DECLARATION_FUNCTION, DECLARATION_METHOD:
+		parameters := parameterList(declaration.Node.(*FuncDecl))
+		if declaration.Kind == DECLARATION_METHOD {
+			return fmt.Sprintf("%s(%s)", name, parameters)
+		}
+		return fmt.Sprintf("fun %s(%s)", name, parameters)
+	case DECLARATION_CLASS:
+		class := declaration.Node.(*ClassDecl)
+		if class.Parent != nil {
+			parent, _ := class.Parent.Value.(string)
+			return fmt.Sprintf("class %s < %s", name, parent)
+		}
+		return fmt.Sprintf("class %s", name)
+	case DECLARATION_PARAMETER:
+		return name
+	}
+	return name
+}
+
+func parameterList(function *FuncDecl) string {
+	parameters := make([]string, 0, len(function.Parameters))
+	for _, parameter := range function.Parameters {
+		parameterName, ok := parameter.(*Variable).Identifier.Value.(string)
+		if ok {
+			parameters = append(parameters, parameterName)
+		}
+	}
+	return strings.Join(parameters, ", ")
+}
+
+// Documentation joins the comments above the declaration, one comment per line
+func (declaration *Declaration) Documentation() string {
+	lines := make([]string, 0, len(declaration.Comments))
+	for _, comment := range declaration.Comments {
+		value, ok := comment.Value.(string)
+		if !ok {
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FindClass returns the class declared with name, preferring global declarations
+func FindClass(declarations []*Declaration, name string) *Declaration {
+	var found *Declaration
+	for _, declaration := range declarations {
+		className, ok := declaration.Name.Value.(string)
+		if declaration.Kind != DECLARATION_CLASS || !ok || className != name {
+			continue
+		}
+		if found == nil || (found.Parent != nil && declaration.Parent == nil) {
+			found = declaration
+		}
+	}
+	return found
+}
+
+// FindMethod looks a method up on a class and then along its superclasses
+func FindMethod(declarations []*Declaration, class *Declaration, name string) *Declaration {
+	visited := make(map[*Declaration]bool)
+	for class != nil && !visited[class] {
+		visited[class] = true
+		for _, declaration := range declarations {
+			methodName, ok := declaration.Name.Value.(string)
+			if declaration.Kind == DECLARATION_METHOD && declaration.Parent == class && ok && methodName == name {
+				return declaration
+			}
+		}
+		parent := class.Node.(*ClassDecl).Parent
+		if parent == nil {
+			return nil
+		}
+		parentName, _ := parent.Value.(string)
+		class = FindClass(declarations, parentName)
+	}
+	return nil
+}
+
+type declarationCollector struct {
+	declarations []*Declaration
+	parent       *Declaration
+	comments     []Token
+}
+
+func (collector *declarationCollector) add(name Token, kind int, node Node) *Declaration {
+	declaration := &Declaration{Name: name, Kind: kind, Node: node, Parent: collector.parent, Comments: collector.comments}
+	collector.comments = nil
+	collector.declarations = append(collector.declarations, declaration)
+	return declaration
+}
+
+// visitBody tracks comment runs in a statement list so they can be attached to the declaration below them
+func (collector *declarationCollector) visitBody(body []Node) {
+	comments := make([]Token, 0)
+	newLines := 0
+	afterCode := false
+	for _, node := range body {
+		switch node := node.(type) {
+		case *NewLine:
+			newLines++
+			continue
+		case *Comment:
+			if afterCode && newLines == 0 { // trailing comment of the previous statement
+				comments = make([]Token, 0)
+			} else if newLines > 1 {
+				comments = []Token{node.Comment}
+			} else {
+				comments = append(comments, node.Comment)
+			}
+			newLines = 0
+			afterCode = false
+			continue
+		}
+		if newLines > 1 {
+			comments = make([]Token, 0)
+		}
+		collector.comments = comments
+		node.Accept(collector)
+		collector.comments = nil
+		comments = make([]Token, 0)
+		newLines = 0
+		afterCode = true
+	}
+}
+
+func (collector *declarationCollector) visitVarDecl(varDecl *VarDecl) {
+	collector.add(varDecl.Identifier, DECLARATION_VARIABLE, varDecl)
+}
+
+func (collector *declarationCollector) visitFuncDecl(function *FuncDecl) {
+	kind := DECLARATION_FUNCTION
+	if function.FunctionType == METHOD_CONTEXT {
+		kind = DECLARATION_METHOD
+	}
+	declaration := collector.add(function.Name, kind, function)
+
+	parent := collector.parent
+	collector.parent = declaration
+	for _, parameter := range function.Parameters {
+		variable, ok := parameter.(*Variable)
+		if !ok {
+			continue
+		}
+		collector.add(variable.Identifier, DECLARATION_PARAMETER, variable)
+	}
+	function.Body.Accept(collector)
+	collector.parent = parent
+}
+
+func (collector *declarationCollector) visitClassDecl(class *ClassDecl) {
+	declaration := collector.add(class.Name, DECLARATION_CLASS, class)
+
+	parent := collector.parent
+	collector.parent = declaration
+	collector.visitBody(class.Body)
+	collector.parent = parent
+}
+
+func (collector *declarationCollector) visitBlock(block *BlockStmt) {
+	collector.comments = nil
+	collector.visitBody(block.Body)
+}
+
+func (collector *declarationCollector) visitIf(ifStmt *IfStmt) {
+	collector.comments = nil
+	ifStmt.Then.Accept(collector)
+	if ifStmt.Else != nil {
+		ifStmt.Else.Accept(collector)
+	}
+}
+
+func (collector *declarationCollector) visitWhile(while *WhileStmt) {
+	collector.comments = nil
+	while.Then.Accept(collector)
+}
+
+func (collector *declarationCollector) visitFor(forStmt *ForStmt) {
+	collector.comments = nil
+	if forStmt.Initializer != nil {
+		forStmt.Initializer.Accept(collector)
+	}
+	forStmt.Body.Accept(collector)
+}
+
+// expressions and simple statements hold no declarations
+func (collector *declarationCollector) visitPrimary(*Primary)         {}
+func (collector *declarationCollector) visitBinary(*Binary)           {}
+func (collector *declarationCollector) visitUnary(*Unary)             {}
+func (collector *declarationCollector) visitGroup(*Group)             {}
+func (collector *declarationCollector) visitVariable(*Variable)       {}
+func (collector *declarationCollector) visitThis(*This)               {}
+func (collector *declarationCollector) visitSuper(*Super)             {}
+func (collector *declarationCollector) visitAssignment(*Assignment)   {}
+func (collector *declarationCollector) visitCall(*Call)               {}
+func (collector *declarationCollector) visitGetExpr(*GetExpr)         {}
+func (collector *declarationCollector) visitSetExpr(*SetExpr)         {}
+func (collector *declarationCollector) visitExprStmt(*ExpressionStmt) {}
+func (collector *declarationCollector) visitPrint(*PrintStmt)         {}
+func (collector *declarationCollector) visitReturn(*ReturnStmt)       {}
+func (collector *declarationCollector) visitNewLine(*NewLine)         {}
+func (collector *declarationCollector) visitComment(*Comment)         {}
@@ -171,6 +171,16 @@ func (formatter *Formatter) visitGetExpr(getExpr *GetExpr) {
 	formatter.write(fmt.Sprintf(".%s", name))
 }
 
+func (formatter *Formatter) visitSetExpr(setExpr *SetExpr) {
+	setExpr.Object.Accept(formatter)
+	name, ok := setExpr.Property.Value.(string)
+	if !ok {
+		return
+	}
+	formatter.write(fmt.Sprintf(".%s = ", name))
+	setExpr.Value.Accept(formatter)
+}
+
 func (formatter *Formatter) visitExprStmt(exprStmt *ExpressionStmt) {
 	formatter.addIndentation()
 	exprStmt.Expr.Accept(formatter)
@@ -0,0 +1,66 @@
+package lox
+
+// Inspect walks the nodes depth first in source order, the children of a node
+// are skipped when visit returns false
+func Inspect(nodes []Node, visit func(Node) bool) {
+	walker := inspector{visit: visit}
+	walker.walk(nodes...)
+}
+
+type inspector struct {
+	visit func(Node) bool
+}
+
+func (walker *inspector) walk(nodes ...Node) {
+	for _, node := range nodes {
+		if node != nil && walker.visit(node) {
+			node.Accept(walker)
+		}
+	}
+}
+
+func (walker *inspector) visitBinary(binary *Binary)         { walker.walk(binary.Left, binary.Right) }
+func (walker *inspector) visitUnary(unary *Unary)            { walker.walk(unary.Expression) }
+func (walker *inspector) visitGroup(group *Group)            { walker.walk(group.Expression) }
+func (walker *inspector) visitGetExpr(get *GetExpr)          { walker.walk(get.Object) }
+func (walker *inspector) visitSetExpr(set *SetExpr)          { walker.walk(set.Object, set.Value) }
+func (walker *inspector) visitExprStmt(stmt *ExpressionStmt) { walker.walk(stmt.Expr) }
+func (walker *inspector) visitPrint(stmt *PrintStmt)         { walker.walk(stmt.Expr) }
+func (walker *inspector) visitReturn(stmt *ReturnStmt)       { walker.walk(stmt.Expr) }
+func (walker *inspector) visitBlock(block *BlockStmt)        { walker.walk(block.Body...) }
+func (walker *inspector) visitVarDecl(decl *VarDecl)         { walker.walk(decl.Value) }
+func (walker *inspector) visitClassDecl(decl *ClassDecl)     { walker.walk(decl.Body...) }
+
+func (walker *inspector) visitAssignment(assignment *Assignment) {
+	walker.walk(assignment.Identifier, assignment.Value)
+}
+
+func (walker *inspector) visitCall(call *Call) {
+	walker.walk(call.Callee)
+	walker.walk(call.Argument...)
+}
+
+func (walker *inspector) visitIf(stmt *IfStmt) {
+	walker.walk(stmt.Condition, stmt.Then, stmt.Else)
+}
+
+func (walker *inspector) visitWhile(stmt *WhileStmt) {
+	walker.walk(stmt.Condition, stmt.Then)
+}
+
+func (walker *inspector) visitFor(stmt *ForStmt) {
+	walker.walk(stmt.Initializer, stmt.Condition, stmt.Assignment, stmt.Body)
+}
+
+func (walker *inspector) visitFuncDecl(decl *FuncDecl) {
+	walker.walk(decl.Parameters...)
+	walker.walk(decl.Body)
+}
+
+// leaves
+func (walker *inspector) visitPrimary(*Primary)   {}
+func (walker *inspector) visitVariable(*Variable) {}
+func (walker *inspector) visitThis(*This)         {}
+func (walker *inspector) visitSuper(*Super)       {}
+func (walker *inspector) visitNewLine(*NewLine)   {}
+func (walker *inspector) visitComment(*Comment)   {}
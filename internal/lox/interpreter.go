@@ -0,0 +1,375 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+/* tree walking interpreter over the parsed AST */
+
+const maxCallDepth = 4096
+
+type Interpreter struct {
+	globals     *environment
+	environment *environment
+	output      io.Writer
+	value       any // result of the last evaluated expression
+	callDepth   int
+}
+
+func (interpreter *Interpreter) initialize(output io.Writer) {
+	interpreter.globals = newEnvironment(nil)
+	for _, native := range natives {
+		interpreter.globals.define(native.name, Token{}, native)
+	}
+	interpreter.environment = interpreter.globals
+	interpreter.output = output
+	interpreter.callDepth = 0
+}
+
+// Interpret runs a program that parsed without errors, stopping at the first runtime error
+func (interpreter *Interpreter) Interpret(ast []Node, output io.Writer) (err error) {
+	interpreter.initialize(output)
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		runtimeError, ok := recovered.(RuntimeError)
+		if !ok {
+			panic(recovered)
+		}
+		err = runtimeError
+	}()
+
+	for _, node := range ast {
+		interpreter.execute(node)
+	}
+	return nil
+}
+
+func (interpreter *Interpreter) execute(node Node) {
+	if node != nil {
+		node.Accept(interpreter)
+	}
+}
+
+func (interpreter *Interpreter) evaluate(node Node) any {
+	interpreter.value = nil
+	node.Accept(interpreter)
+	return interpreter.value
+}
+
+func (interpreter *Interpreter) executeBlock(body []Node, env *environment) {
+	previous := interpreter.environment
+	defer func() { interpreter.environment = previous }()
+
+	interpreter.environment = env
+	for _, node := range body {
+		interpreter.execute(node)
+	}
+}
+
+func (interpreter *Interpreter) visitNewLine(*NewLine) {}
+
+func (interpreter *Interpreter) visitComment(*Comment) {}
+
+func (interpreter *Interpreter) visitPrimary(primary *Primary) {
+	switch value := primary.Value.(type) {
+	case int:
+		interpreter.value = float64(value)
+	default:
+		interpreter.value = value
+	}
+}
+
+func (interpreter *Interpreter) visitGroup(group *Group) {
+	interpreter.value = interpreter.evaluate(group.Expression)
+}
+
+func (interpreter *Interpreter) visitUnary(unary *Unary) {
+	operand := interpreter.evaluate(unary.Expression)
+	switch unary.Operation {
+	case MINUS:
+		interpreter.value = -checkNumber(unary.Operator, operand)
+	case BANG:
+		interpreter.value = !isTruthy(operand)
+	}
+}
+
+func (interpreter *Interpreter) visitBinary(binary *Binary) {
+	left := interpreter.evaluate(binary.Left)
+
+	// logical operators short circuit and yield one of their operands
+	switch binary.Operation {
+	case OR:
+		if isTruthy(left) {
+			interpreter.value = left
+			return
+		}
+		interpreter.value = interpreter.evaluate(binary.Right)
+		return
+	case AND:
+		if !isTruthy(left) {
+			interpreter.value = left
+			return
+		}
+		interpreter.value = interpreter.evaluate(binary.Right)
+		return
+	}
+
+	right := interpreter.evaluate(binary.Right)
+	switch binary.Operation {
+	case EQUALEQUAL:
+		interpreter.value = left == right
+	case BANGEQUAL:
+		interpreter.value = left != right
+	case PLUS:
+		leftString, leftIsString := left.(string)
+		rightString, rightIsString := right.(string)
+		if leftIsString && rightIsString {
+			interpreter.value = leftString + rightString
+			return
+		}
+		leftNumber, leftIsNumber := left.(float64)
+		rightNumber, rightIsNumber := right.(float64)
+		if leftIsNumber && rightIsNumber {
+			interpreter.value = leftNumber + rightNumber
+			return
+		}
+		panic(RuntimeError{Message: "Operands must be two numbers or two strings.", Token: binary.Operator})
+	case MINUS:
+		leftNumber, rightNumber := checkNumbers(binary.Operator, left, right)
+		interpreter.value = leftNumber - rightNumber
+	case STAR:
+		leftNumber, rightNumber := checkNumbers(binary.Operator, left, right)
+		interpreter.value = leftNumber * rightNumber
+	case SLASH:
+		leftNumber, rightNumber := checkNumbers(binary.Operator, left, right)
+		interpreter.value = leftNumber / rightNumber
+	case GREATER:
+		leftNumber, rightNumber := checkNumbers(binary.Operator, left, right)
+		interpreter.value = leftNumber > rightNumber
+	case GREATEREQUAL:
+		leftNumber, rightNumber := checkNumbers(binary.Operator, left, right)
+		interpreter.value = leftNumber >= rightNumber
+	case LESS:
+		leftNumber, rightNumber := checkNumbers(binary.Operator, left, right)
+		interpreter.value = leftNumber < rightNumber
+	case LESSEQUAL:
+		leftNumber, rightNumber := checkNumbers(binary.Operator, left, right)
+		interpreter.value = leftNumber <= rightNumber
+	}
+}
+
+func (interpreter *Interpreter) visitVariable(variable *Variable) {
+	interpreter.value = interpreter.lookUp(variable.Identifier, variable.Definition)
+}
+
+func (interpreter *Interpreter) visitThis(this *This) {
+	interpreter.value = interpreter.lookUpName("this", this.Identifier)
+}
+
+func (interpreter *Interpreter) visitSuper(super *Super) {
+	superclass, _ := interpreter.lookUpName("super", super.Identifier).(*loxClass)
+	instance, _ := interpreter.lookUpName("this", super.Identifier).(*loxInstance)
+	name, _ := super.Property.Value.(string)
+	method := superclass.findMethod(name)
+	if method == nil {
+		panic(RuntimeError{Message: fmt.Sprintf("Undefined property '%s'.", name), Token: super.Property})
+	}
+	interpreter.value = method.bind(instance)
+}
+
+func (interpreter *Interpreter) visitAssignment(assignment *Assignment) {
+	value := interpreter.evaluate(assignment.Value)
+	variable := assignment.Identifier.(*Variable)
+	name, _ := variable.Identifier.Value.(string)
+	env := interpreter.environment.find(name, variable.Definition)
+	if env == nil {
+		panic(RuntimeError{Message: fmt.Sprintf("Undefined variable '%s'.", name), Token: variable.Identifier})
+	}
+	env.define(name, env.values[name].declaration, value)
+	interpreter.value = value
+}
+
+func (interpreter *Interpreter) visitCall(call *Call) {
+	callee := interpreter.evaluate(call.Callee)
+	arguments := make([]any, 0, len(call.Argument))
+	for _, argument := range call.Argument {
+		arguments = append(arguments, interpreter.evaluate(argument))
+	}
+
+	function, ok := callee.(callable)
+	if !ok {
+		panic(RuntimeError{Message: "Can only call functions and classes.", Token: call.Paren})
+	}
+	if len(arguments) != function.arity() {
+		panic(RuntimeError{Message: fmt.Sprintf("Expected %d arguments but got %d.", function.arity(), len(arguments)), Token: call.Paren})
+	}
+	if interpreter.callDepth >= maxCallDepth {
+		panic(RuntimeError{Message: "Stack overflow.", Token: call.Paren})
+	}
+
+	interpreter.callDepth++
+	defer func() { interpreter.callDepth-- }()
+	interpreter.value = function.call(interpreter, arguments)
+}
+
+func (interpreter *Interpreter) visitGetExpr(get *GetExpr) {
+	object := interpreter.evaluate(get.Object)
+	instance, ok := object.(*loxInstance)
+	if !ok {
+		panic(RuntimeError{Message: "Only instances have properties.", Token: get.Property})
+	}
+	interpreter.value = instance.get(get.Property)
+}
+
+func (interpreter *Interpreter) visitSetExpr(set *SetExpr) {
+	object := interpreter.evaluate(set.Object)
+	instance, ok := object.(*loxInstance)
+	if !ok {
+		panic(RuntimeError{Message: "Only instances have fields.", Token: set.Property})
+	}
+	value := interpreter.evaluate(set.Value)
+	instance.set(set.Property, value)
+	interpreter.value = value
+}
+
+func (interpreter *Interpreter) visitExprStmt(stmt *ExpressionStmt) {
+	interpreter.evaluate(stmt.Expr)
+}
+
+func (interpreter *Interpreter) visitPrint(stmt *PrintStmt) {
+	fmt.Fprintln(interpreter.output, stringify(interpreter.evaluate(stmt.Expr)))
+}
+
+func (interpreter *Interpreter) visitReturn(stmt *ReturnStmt) {
+	panic(returnValue{value: interpreter.evaluate(stmt.Expr)})
+}
+
+func (interpreter *Interpreter) visitBlock(block *BlockStmt) {
+	interpreter.executeBlock(block.Body, newEnvironment(interpreter.environment))
+}
+
+func (interpreter *Interpreter) visitIf(stmt *IfStmt) {
+	if isTruthy(interpreter.evaluate(stmt.Condition)) {
+		interpreter.execute(stmt.Then)
+	} else {
+		interpreter.execute(stmt.Else)
+	}
+}
+
+func (interpreter *Interpreter) visitVarDecl(decl *VarDecl) {
+	name, _ := decl.Identifier.Value.(string)
+	interpreter.environment.define(name, decl.Identifier, interpreter.evaluate(decl.Value))
+}
+
+func (interpreter *Interpreter) visitWhile(stmt *WhileStmt) {
+	for isTruthy(interpreter.evaluate(stmt.Condition)) {
+		interpreter.execute(stmt.Then)
+	}
+}
+
+func (interpreter *Interpreter) visitFor(stmt *ForStmt) {
+	previous := interpreter.environment
+	defer func() { interpreter.environment = previous }()
+
+	interpreter.environment = newEnvironment(previous)
+	interpreter.execute(stmt.Initializer)
+	for isTruthy(interpreter.evaluate(stmt.Condition)) {
+		interpreter.execute(stmt.Body)
+		if stmt.Assignment != nil {
+			interpreter.evaluate(stmt.Assignment)
+		}
+	}
+}
+
+func (interpreter *Interpreter) visitFuncDecl(decl *FuncDecl) {
+	name, _ := decl.Name.Value.(string)
+	function := &loxFunction{declaration: decl, closure: interpreter.environment}
+	interpreter.environment.define(name, decl.Name, function)
+}
+
+func (interpreter *Interpreter) visitClassDecl(decl *ClassDecl) {
+	name, _ := decl.Name.Value.(string)
+	class := &loxClass{name: name, methods: make(map[string]*loxFunction)}
+
+	closure := interpreter.environment
+	if decl.Parent != nil {
+		superclass, ok := interpreter.lookUp(*decl.Parent, Token{}).(*loxClass)
+		if !ok {
+			panic(RuntimeError{Message: "Superclass must be a class.", Token: *decl.Parent})
+		}
+		class.superclass = superclass
+		closure = newEnvironment(closure)
+		closure.define("super", Token{}, superclass)
+	}
+
+	for _, node := range decl.Body {
+		method, ok := node.(*FuncDecl)
+		if !ok {
+			continue
+		}
+		methodName, _ := method.Name.Value.(string)
+		class.methods[methodName] = &loxFunction{declaration: method, closure: closure, isInitializer: methodName == "init"}
+	}
+	interpreter.environment.define(name, decl.Name, class)
+}
+
+func (interpreter *Interpreter) lookUp(identifier Token, declaration Token) any {
+	name, _ := identifier.Value.(string)
+	env := interpreter.environment.find(name, declaration)
+	if env == nil {
+		panic(RuntimeError{Message: fmt.Sprintf("Undefined variable '%s'.", name), Token: identifier})
+	}
+	return env.values[name].value
+}
+
+func (interpreter *Interpreter) lookUpName(name string, token Token) any {
+	env := interpreter.environment.find(name, Token{})
+	if env == nil {
+		panic(RuntimeError{Message: fmt.Sprintf("Undefined variable '%s'.", name), Token: token})
+	}
+	return env.values[name].value
+}
+
+func isTruthy(value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	default:
+		return true
+	}
+}
+
+func checkNumber(operator Token, operand any) float64 {
+	number, ok := operand.(float64)
+	if !ok {
+		panic(RuntimeError{Message: "Operand must be a number.", Token: operator})
+	}
+	return number
+}
+
+func checkNumbers(operator Token, left any, right any) (float64, float64) {
+	leftNumber, leftOk := left.(float64)
+	rightNumber, rightOk := right.(float64)
+	if !leftOk || !rightOk {
+		panic(RuntimeError{Message: "Operands must be numbers.", Token: operator})
+	}
+	return leftNumber, rightNumber
+}
+
+func stringify(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,93 @@
+package lox
+
+import "fmt"
+
+const (
+	MEMBER_METHOD = iota
+	MEMBER_FIELD
+)
+
+type Member struct {
+	Name  Token // method name or the property of the first this.field assignment
+	Kind  int
+	Node  Node         // *FuncDecl of a method or the *SetExpr declaring a field
+	Class *Declaration // class the member is declared in
+}
+
+// Members lists the methods and fields of a class followed by the ones it inherits,
+// members overridden in a subclass are listed once
+func Members(declarations []*Declaration, class *Declaration) []Member {
+	members := make([]Member, 0)
+	seen := make(map[string]bool)
+	add := func(member Member) {
+		name, ok := member.Name.Value.(string)
+		if !ok || seen[name] {
+			return
+		}
+		seen[name] = true
+		members = append(members, member)
+	}
+
+	visited := make(map[*Declaration]bool)
+	for class != nil && !visited[class] {
+		visited[class] = true
+		for _, member := range ClassMembers(declarations, class) {
+			add(member)
+		}
+		parent := class.Node.(*ClassDecl).Parent
+		if parent == nil {
+			break
+		}
+		parentName, _ := parent.Value.(string)
+		class = FindClass(declarations, parentName)
+	}
+	return members
+}
+
+func (member Member) Signature() string {
+	name, _ := member.Name.Value.(string)
+	if member.Kind == MEMBER_METHOD {
+		return fmt.Sprintf("%s(%s)", name, parameterList(member.Node.(*FuncDecl)))
+	}
+	return name
+}
+
+// ClassMembers lists the members declared by the class itself, methods first
+func ClassMembers(declarations []*Declaration, class *Declaration) []Member {
+	members := make([]Member, 0)
+	methods := make([]*FuncDecl, 0)
+	for _, declaration := range declarations {
+		if declaration.Kind == DECLARATION_METHOD && declaration.Parent == class {
+			method := declaration.Node.(*FuncDecl)
+			methods = append(methods, method)
+			members = append(members, Member{Name: declaration.Name, Kind: MEMBER_METHOD, Node: method, Class: class})
+		}
+	}
+
+	fields := make([]*SetExpr, 0)
+	for _, method := range methods {
+		Inspect([]Node{method.Body}, func(node Node) bool {
+			switch node := node.(type) {
+			case *ClassDecl:
+				return false // this inside a nested class refers to that class
+			case *SetExpr:
+				if _, ok := node.Object.(*This); ok {
+					fields = append(fields, node)
+				}
+			}
+			return true
+		})
+	}
+	declared := make(map[any]bool)
+	for _, member := range members {
+		declared[member.Name.Value] = true
+	}
+	for _, field := range fields {
+		if declared[field.Property.Value] {
+			continue
+		}
+		declared[field.Property.Value] = true
+		members = append(members, Member{Name: field.Property, Kind: MEMBER_FIELD, Node: field, Class: class})
+	}
+	return members
+}
@@ -3,6 +3,7 @@ package lox
 import (
 	"fmt"
 	"slices"
+	"strings"
 )
 
 /*
@@ -48,6 +49,22 @@ import (
 
 var NativeFunctions []string = []string{"clock"}
 
+var nativeArities map[string]int = map[string]int{"clock": 0}
+
+// a property access resolved once every class has been parsed
+type pendingProperty struct {
+	node     Node // *GetExpr or *Super
+	property Token
+	class    Token // enclosing class, zero outside of classes
+}
+
+// a call whose arity is checked once every declaration has been parsed
+type pendingCall struct {
+	callee    Variable
+	arguments int
+	span      Span
+}
+
 type SymbolMap struct {
 	currentTable    map[string]Token
 	previous        *SymbolMap
@@ -78,6 +95,15 @@ type Parser struct {
 	scopeTable      map[ScopeRange][]Token
 	scopeRanges     []ScopeRange
 	panicMode       bool
+	arities         map[Token]int // parameter count of functions and class initializers
+	calls           []pendingCall
+	classes         map[Token]bool
+	superclasses    map[Token]Token // class definitions mapped to the definition of their superclass
+	hasSuperclass   bool            // the enclosing class inherits, so super can be used
+	currentClass    Token
+	methods         map[Token]map[string]Token // class definitions mapped to their methods by name
+	properties      []pendingProperty
+	ambiguousUses   map[Token]bool // methods an access on an object of unknown class may refer to
 }
 
 func (parser *Parser) initialize(input []Token) {
@@ -92,6 +118,15 @@ func (parser *Parser) initialize(input []Token) {
 	}
 	parser.references = make(map[Token][]Token)
 	parser.scopeTable = map[ScopeRange][]Token{}
+	parser.arities = make(map[Token]int)
+	parser.calls = make([]pendingCall, 0)
+	parser.classes = make(map[Token]bool)
+	parser.superclasses = make(map[Token]Token)
+	parser.hasSuperclass = false
+	parser.currentClass = Token{}
+	parser.methods = make(map[Token]map[string]Token)
+	parser.properties = make([]pendingProperty, 0)
+	parser.ambiguousUses = make(map[Token]bool)
 }
 
 func (parser *Parser) isGlobal() bool { return parser.symbolMap.currScope == 0 }
@@ -160,9 +195,9 @@ func (parser *Parser) addDefinition(token Token) {
 	if ok {
 		definition, isPresent := parser.getDefinitionInScope(name)
 		if isPresent && parser.isGlobal() {
-			parser.addWarning(fmt.Sprintf("%s is already declared in this scope at line %d", name, definition.Line+1))
+			parser.addWarningAt(fmt.Sprintf("%s is already declared in this scope at line %d", name, definition.Line+1), token, CODE_DUPLICATE_DECLARATION)
 		} else if isPresent {
-			parser.addError(fmt.Sprintf("%s is already declared in this scope at line %d", name, definition.Line+1), ERROR_RESOLVER)
+			parser.addErrorAt(fmt.Sprintf("%s is already declared in this scope at line %d", name, definition.Line+1), token, token, ERROR_RESOLVER, CODE_DUPLICATE_DECLARATION)
 		}
 		parser.symbolMap.currentTable[name] = token
 		parser.references[token] = []Token{}
@@ -177,11 +212,13 @@ func (parser *Parser) Parse(input []Token) ([]Node, []Node, map[Token][]Token, m
 	for token := parser.peekParser(); token.TokenType != EOF; token = parser.peekParser() {
 		program = append(program, parser.declaration())
 	}
+	parser.resolveProperties()
 	for name := range parser.references {
-		if len(parser.references[name]) == 0 {
-			parser.addWarningAt("No usages after definition", name.Line, name.Character)
+		if len(parser.references[name]) == 0 && !parser.ambiguousUses[name] {
+			parser.addWarningAt("No usages after definition", name, CODE_UNUSED_DEFINITION)
 		}
 	}
+	parser.checkArities()
 	// add global definitions to scope table
 	parser.scopeTable[ScopeRange{FunctionContext: GLOBAL_CONTEXT, ClassContext: GLOBAL_CONTEXT, ScopeContext: GLOBAL_CONTEXT}] = parser.symbolMap.definitions
 	return program, parser.identifierNodes, parser.references, parser.scopeTable, parser.errorList
@@ -197,25 +234,31 @@ func (parser *Parser) declaration() Node {
 	case parser.match(CLASS):
 		return parser.classDeclaration()
 	case parser.match(NEWLINE):
-		return &NewLine{Token: parser.peekPrevious()}
+		return &NewLine{Span: parser.spanFrom(parser.peekPrevious()), Token: parser.peekPrevious()}
 	case parser.match(COMMENT):
-		return &Comment{Comment: parser.peekPrevious()}
+		return &Comment{Span: parser.spanFrom(parser.peekPrevious()), Comment: parser.peekPrevious()}
 	default:
 		return parser.statement(GLOBAL_CONTEXT)
 	}
 }
 
 func (parser *Parser) classDeclaration() Node {
+	keyword := parser.peekPrevious()
 	identifier := parser.peekParser()
 	parser.addDefinition(identifier)
 	parser.consume(IDENTIFIER, "Expected identifier for class name")
+	parser.classes[identifier] = true
 
 	var parent *Token
 	if parser.match(LESS) {
 		token := parser.peekParser()
-		parser.consume(IDENTIFIER, "Expected identifier for class name")
+		if parser.consume(IDENTIFIER, "Expected identifier for class name") {
+			parser.superclass(identifier, token)
+		}
 		parent = &token
 	}
+	enclosingSuperclass, enclosingClass := parser.hasSuperclass, parser.currentClass
+	parser.hasSuperclass, parser.currentClass = parent != nil, identifier
 
 	parser.consume(BRACELEFT, "Expected '{' before class body")
 	brace := parser.peekPrevious()
@@ -224,12 +267,12 @@ func (parser *Parser) classDeclaration() Node {
 	for token := parser.peekParser().TokenType; token != BRACERIGHT && token != EOF; token = parser.peekParser().TokenType {
 		if token == NEWLINE {
 			parser.match(NEWLINE)
-			methods = append(methods, &NewLine{Token: parser.peekPrevious()})
+			methods = append(methods, &NewLine{Span: parser.spanFrom(parser.peekPrevious()), Token: parser.peekPrevious()})
 			continue
 		}
 		if token == COMMENT {
 			parser.match(COMMENT)
-			methods = append(methods, &Comment{Comment: parser.peekPrevious(), Inline: false})
+			methods = append(methods, &Comment{Span: parser.spanFrom(parser.peekPrevious()), Comment: parser.peekPrevious(), Inline: false})
 			continue
 		}
 		method := parser.funcDeclaration(METHOD_CONTEXT)
@@ -243,10 +286,72 @@ func (parser *Parser) classDeclaration() Node {
 	brace = parser.peekPrevious()
 	parser.closeScope(brace.Line, brace.Character)
 
-	return &ClassDecl{Body: methods, Name: identifier, Parent: parent}
+	parser.hasSuperclass, parser.currentClass = enclosingSuperclass, enclosingClass
+
+	parser.methods[identifier] = make(map[string]Token)
+	for _, method := range methods {
+		if function, ok := method.(*FuncDecl); ok {
+			if name, ok := function.Name.Value.(string); ok {
+				parser.methods[identifier][name] = function.Name
+			}
+		}
+	}
+
+	if parent == nil {
+		parser.arities[identifier] = 0
+	} else if arity, ok := parser.arities[parser.superclasses[identifier]]; ok {
+		parser.arities[identifier] = arity
+	}
+	for _, method := range methods {
+		if function, ok := method.(*FuncDecl); ok && function.Name.Value == "init" {
+			parser.arities[identifier] = len(function.Parameters)
+		}
+	}
+
+	return &ClassDecl{Span: parser.tokenSpan(keyword, brace), Body: methods, Name: identifier, Parent: parent}
+}
+
+// superclass resolves the parent of a class and reports what it can not inherit from
+func (parser *Parser) superclass(class Token, parent Token) {
+	name, _ := parent.Value.(string)
+	if slices.Contains(NativeFunctions, name) {
+		parser.addErrorAt(fmt.Sprintf("%s is not a class", name), parent, parent, ERROR_RESOLVER, "")
+		return
+	}
+	definition, ok := parser.getDefinition(name)
+	if !ok {
+		parser.addErrorAt(fmt.Sprintf("Superclass %s is not defined in current scope", name), parent, parent, ERROR_RESOLVER, "")
+		return
+	}
+	parser.addIdentifier(&Variable{Span: parser.tokenSpan(parent, parent), Identifier: parent, Definition: definition}, definition, parent)
+
+	if definition == class {
+		parser.addErrorAt("A class can't inherit from itself", parent, parent, ERROR_RESOLVER, "")
+		return
+	}
+	if !parser.classes[definition] {
+		parser.addErrorAt(fmt.Sprintf("%s is not a class", name), parent, parent, ERROR_RESOLVER, "")
+		return
+	}
+
+	// classes are matched by name along the chain, so a redeclared class can close a cycle
+	className, _ := class.Value.(string)
+	chain := []string{className, name}
+	visited := map[Token]bool{definition: true}
+	for ancestor, ok := parser.superclasses[definition]; ok && !visited[ancestor]; ancestor, ok = parser.superclasses[ancestor] {
+		visited[ancestor] = true
+		ancestorName, _ := ancestor.Value.(string)
+		chain = append(chain, ancestorName)
+		if ancestorName == className {
+			parser.addErrorAt(fmt.Sprintf("Inheritance cycle %s", strings.Join(chain, " < ")), parent, parent, ERROR_RESOLVER, "")
+			return
+		}
+	}
+	parser.superclasses[class] = definition
 }
 
 func (parser *Parser) varDeclaration() Node {
+	keyword := parser.peekPrevious()
 	identifier := parser.peekParser()
 	parser.addDefinition(identifier)
 	parser.consume(IDENTIFIER, "Expected identifier after var declaration")
@@ -259,16 +364,24 @@ func (parser *Parser) varDeclaration() Node {
 	}
 	parser.consume(SEMICOLON, "Expected ; at end of statement")
 
-	return &VarDecl{Identifier: identifier, Value: value, Initialized: initialzied}
+	return &VarDecl{Span: parser.spanFrom(keyword), Identifier: identifier, Value: value, Initialized: initialzied}
 
 }
 
 func (parser *Parser) funcDeclaration(functionContext int) Node {
+	// methods have no leading keyword
+	start := parser.peekPrevious()
+	if functionContext == METHOD_CONTEXT {
+		start = parser.peekParser()
+	}
 	identifier := parser.peekParser()
 	parser.addDefinition(identifier)
 	parser.consume(IDENTIFIER, "Expected identifier for function name")
 
 	parser.consume(PARANLEFT, "Expected ( after function name")
+	// parameters are scoped to the function body
+	paren := parser.peekPrevious()
+	parser.raiseScope(paren.Line, paren.Character, functionContext)
 	parameters := make([]Node, 0)
 	if !parser.match(PARANRIGHT) {
 		parameters = parser.parameters()
@@ -276,23 +389,28 @@ func (parser *Parser) funcDeclaration(functionContext int) Node {
 
 	parser.consume(BRACELEFT, "Expected { at start of function body")
 
-	body := parser.block(functionContext)
+	if functionContext == FUNCTION_CONTEXT {
+		parser.arities[identifier] = len(parameters)
+	}
+	body := parser.blockBody(functionContext)
+	brace := parser.peekPrevious()
+	parser.closeScope(brace.Line, brace.Character)
 
-	return &FuncDecl{Name: identifier, Body: body, Parameters: parameters, FunctionType: functionContext}
+	return &FuncDecl{Span: parser.tokenSpan(start, brace), Name: identifier, Body: body, Parameters: parameters, FunctionType: functionContext}
 
 }
 
 func (parser *Parser) parameters() []Node {
 	parameters := make([]Node, 0)
 	consumed := parser.consume(IDENTIFIER, "Expected Parameter Name")
-	parameters = append(parameters, &Variable{Identifier: parser.peekPrevious()})
+	parameters = append(parameters, &Variable{Span: parser.spanFrom(parser.peekPrevious()), Identifier: parser.peekPrevious()})
 	if consumed {
 		parser.addDefinition(parser.peekPrevious())
 	}
 
 	for parser.match(COMMA) {
 		consumed = parser.consume(IDENTIFIER, "Expected Parameter Name")
-		parameters = append(parameters, &Variable{Identifier: parser.peekPrevious()})
+		parameters = append(parameters, &Variable{Span: parser.spanFrom(parser.peekPrevious()), Identifier: parser.peekPrevious()})
 		if consumed {
 			parser.addDefinition(parser.peekPrevious())
 		}
@@ -305,23 +423,24 @@ func (parser *Parser) parameters() []Node {
 }
 
 func (parser *Parser) statement(scopeContext int) Node {
-	fmt.Printf(">>%s", parser.peekParser().Value)
 	switch {
 	case parser.match(PRINT):
+		keyword := parser.peekPrevious()
 		expr := parser.expression()
 		parser.consume(SEMICOLON, "Expected ; at end of statement")
-		return &PrintStmt{Expr: expr}
+		return &PrintStmt{Span: parser.spanFrom(keyword), Expr: expr}
 
 	case parser.match(RETURN):
+		keyword := parser.peekPrevious()
 		if parser.symbolMap.functionContext == GLOBAL_CONTEXT {
-			parser.addError("Unexpected Return statement outside of functions or methods", ERROR_RESOLVER)
+			parser.addErrorAt("Unexpected Return statement outside of functions or methods", parser.peekPrevious(), parser.peekPrevious(), ERROR_RESOLVER, "")
 		}
 		if parser.match(SEMICOLON) {
-			return &ReturnStmt{Expr: &Primary{ValType: "nil", Value: nil}, ReturnsValue: false}
+			return &ReturnStmt{Span: parser.spanFrom(keyword), Expr: &Primary{ValType: "nil", Value: nil}, ReturnsValue: false}
 		}
 		expr := parser.expression()
 		parser.consume(SEMICOLON, "Expected ; at end of statement")
-		return &ReturnStmt{Expr: expr, ReturnsValue: true}
+		return &ReturnStmt{Span: parser.spanFrom(keyword), Expr: expr, ReturnsValue: true}
 
 	case parser.match(BRACELEFT):
 		if scopeContext == GLOBAL_CONTEXT {
@@ -330,13 +449,13 @@ func (parser *Parser) statement(scopeContext int) Node {
 		return parser.block(scopeContext)
 
 	case parser.match(IF):
-		return parser.ifStmt()
+		return parser.ifStmt(parser.peekPrevious())
 
 	case parser.match(WHILE):
-		return parser.whileStmt()
+		return parser.whileStmt(parser.peekPrevious())
 
 	case parser.match(FOR):
-		return parser.forStmt()
+		return parser.forStmt(parser.peekPrevious())
 
 	default:
 		return parser.exprStmt()
@@ -346,23 +465,29 @@ func (parser *Parser) statement(scopeContext int) Node {
 func (parser *Parser) exprStmt() Node {
 	expr := parser.expression()
 	parser.consume(SEMICOLON, "Expected ; at end of statement")
-	return &ExpressionStmt{Expr: expr}
+	return &ExpressionStmt{Span: parser.spanAfter(expr), Expr: expr}
 }
 
 func (parser *Parser) block(scopeContext int) Node {
 	brace := parser.peekPrevious()
 	parser.raiseScope(brace.Line, brace.Character, scopeContext)
+	block := parser.blockBody(scopeContext)
+	brace = parser.peekPrevious()
+	parser.closeScope(brace.Line, brace.Character)
+	return block
+}
+
+func (parser *Parser) blockBody(scopeContext int) Node {
+	brace := parser.lastToken()
 	body := make([]Node, 0)
 	for token := parser.peekParser(); token.TokenType != EOF && token.TokenType != BRACERIGHT; token = parser.peekParser() {
 		body = append(body, parser.declaration())
 	}
 	parser.consume(BRACERIGHT, "Expected '}' at end of block")
-	brace = parser.peekPrevious()
-	parser.closeScope(brace.Line, brace.Character)
-	return &BlockStmt{Body: body, BlockContext: scopeContext}
+	return &BlockStmt{Span: parser.spanFrom(brace), Body: body, BlockContext: scopeContext}
 }
 
-func (parser *Parser) ifStmt() Node {
+func (parser *Parser) ifStmt(keyword Token) Node {
 	parser.consume(PARANLEFT, "Expected '(' after if")
 	condition := parser.expression()
 	parser.consume(PARANRIGHT, "Expected ')' after condition")
@@ -374,20 +499,20 @@ func (parser *Parser) ifStmt() Node {
 		elseBranch = parser.statement(IF_CONTEXT)
 	}
 
-	return &IfStmt{Condition: condition, Then: thenBranch, Else: elseBranch}
+	return &IfStmt{Span: parser.spanFrom(keyword), Condition: condition, Then: thenBranch, Else: elseBranch}
 }
 
-func (parser *Parser) whileStmt() Node {
+func (parser *Parser) whileStmt(keyword Token) Node {
 	parser.consume(PARANLEFT, "Expected '(' after while")
 	condition := parser.expression()
 	parser.consume(PARANRIGHT, "Expected ')' after condition")
 
 	body := parser.statement(WHILE_CONTEXT)
 
-	return &WhileStmt{Condition: condition, Then: body}
+	return &WhileStmt{Span: parser.spanFrom(keyword), Condition: condition, Then: body}
 }
 
-func (parser *Parser) forStmt() Node {
+func (parser *Parser) forStmt(keyword Token) Node {
 	parser.consume(PARANLEFT, "Expected '(' after for")
 
 	var initializer Node = nil
@@ -414,7 +539,7 @@ func (parser *Parser) forStmt() Node {
 	parser.consume(PARANRIGHT, "Expected ')' before body")
 	body := parser.statement(FOR_CONTEXT)
 
-	return &ForStmt{Initializer: initializer, Condition: condition, Assignment: assign, Body: body}
+	return &ForStmt{Span: parser.spanFrom(keyword), Initializer: initializer, Condition: condition, Assignment: assign, Body: body}
 }
 
 func (parser *Parser) expression() Node {
@@ -425,16 +550,21 @@ func (parser *Parser) assignment() Node {
 	expr := parser.logicalOr()
 
 	if parser.match(EQUAL) {
-
-		token := parser.peekPrevious()
 		value := parser.assignment()
 
-		variable, ok := expr.(*Variable)
-		if !ok {
-			parser.addErrorAt("Invalid assignment target", token.Line, token.Character, ERROR_PARSER)
-			return expr
+		switch target := expr.(type) {
+		case *Variable:
+			target.Write = true
+			expr = &Assignment{Span: parser.spanAfter(target), Identifier: target, Value: value}
+		case *GetExpr:
+			// fields are assigned, not resolved to methods
+			parser.properties = slices.DeleteFunc(parser.properties, func(property pendingProperty) bool {
+				return property.node == Node(target)
+			})
+			expr = &SetExpr{Span: parser.spanAfter(target), Object: target.Object, Property: target.Property, Value: value}
+		default:
+			parser.addErrorSpan("Invalid assignment target", expr.GetSpan(), ERROR_PARSER, "")
 		}
-		expr = &Assignment{Identifier: variable, Value: value}
 	}
 	return expr
 }
@@ -445,7 +575,7 @@ func (parser *Parser) logicalOr() Node {
 	for token := parser.peekParser(); token.TokenType == OR; token = parser.peekParser() {
 		parser.advanceParser(true)
 		right := parser.logicalAnd()
-		expr = &Binary{Left: expr, Right: right, Operation: token.TokenType}
+		expr = &Binary{Span: parser.spanAfter(expr), Left: expr, Right: right, Operation: token.TokenType, Operator: token}
 	}
 
 	return expr
@@ -457,7 +587,7 @@ func (parser *Parser) logicalAnd() Node {
 	for token := parser.peekParser(); token.TokenType == AND; token = parser.peekParser() {
 		parser.advanceParser(true)
 		right := parser.equality()
-		expr = &Binary{Left: expr, Right: right, Operation: token.TokenType}
+		expr = &Binary{Span: parser.spanAfter(expr), Left: expr, Right: right, Operation: token.TokenType, Operator: token}
 	}
 
 	return expr
@@ -469,7 +599,7 @@ func (parser *Parser) equality() Node {
 	for token := parser.peekParser(); token.TokenType == EQUALEQUAL || token.TokenType == BANGEQUAL; token = parser.peekParser() {
 		parser.advanceParser(true)
 		right := parser.comparison()
-		expr = &Binary{Left: expr, Right: right, Operation: token.TokenType}
+		expr = &Binary{Span: parser.spanAfter(expr), Left: expr, Right: right, Operation: token.TokenType, Operator: token}
 	}
 
 	return expr
@@ -482,7 +612,7 @@ func (parser *Parser) comparison() Node {
 		token.TokenType == LESS || token.TokenType == LESSEQUAL; token = parser.peekParser() {
 		parser.advanceParser(true)
 		right := parser.term()
-		expr = &Binary{Left: expr, Right: right, Operation: token.TokenType}
+		expr = &Binary{Span: parser.spanAfter(expr), Left: expr, Right: right, Operation: token.TokenType, Operator: token}
 	}
 
 	return expr
@@ -494,7 +624,7 @@ func (parser *Parser) term() Node {
 	for token := parser.peekParser(); token.TokenType == PLUS || token.TokenType == MINUS; token = parser.peekParser() {
 		parser.advanceParser(true)
 		right := parser.factor()
-		expr = &Binary{Left: expr, Right: right, Operation: token.TokenType}
+		expr = &Binary{Span: parser.spanAfter(expr), Left: expr, Right: right, Operation: token.TokenType, Operator: token}
 	}
 
 	return expr
@@ -506,7 +636,7 @@ func (parser *Parser) factor() Node {
 	for token := parser.peekParser(); token.TokenType == STAR || token.TokenType == SLASH; token = parser.peekParser() {
 		parser.advanceParser(true)
 		right := parser.unary()
-		expr = &Binary{Left: expr, Right: right, Operation: token.TokenType}
+		expr = &Binary{Span: parser.spanAfter(expr), Left: expr, Right: right, Operation: token.TokenType, Operator: token}
 	}
 
 	return expr
@@ -515,7 +645,8 @@ func (parser *Parser) factor() Node {
 func (parser *Parser) unary() Node {
 	if token := parser.peekParser(); token.TokenType == MINUS || token.TokenType == BANG {
 		parser.advanceParser(true)
-		return &Unary{Expression: parser.unary(), Operation: token.TokenType}
+		expression := parser.unary()
+		return &Unary{Span: parser.spanFrom(token), Expression: expression, Operation: token.TokenType, Operator: token}
 	}
 	return parser.call()
 }
@@ -535,21 +666,122 @@ func (parser *Parser) call() Node {
 
 func (parser *Parser) getExpression(object Node) Node {
 	property := parser.peekParser()
-	parser.consume(IDENTIFIER, "Expected Property name")
+	if !parser.consume(IDENTIFIER, "Expected Property name") {
+		return &GetExpr{Span: parser.spanAfter(object), Object: object, Property: property}
+	}
 
-	return &GetExpr{Object: object, Property: property}
+	get := &GetExpr{Span: parser.spanAfter(object), Object: object, Property: property}
+	parser.properties = append(parser.properties, pendingProperty{node: get, property: property, class: parser.currentClass})
+	return get
 }
 
 func (parser *Parser) finishCall(callee Node) Node {
+	paren := parser.peekPrevious()
 	if parser.match(PARANRIGHT) {
-		return &Call{Callee: callee, Argument: make([]Node, 0)}
+		parser.addCall(callee, 0)
+		return &Call{Span: parser.spanAfter(callee), Callee: callee, Argument: make([]Node, 0), Paren: parser.peekPrevious()}
 	}
 	arguments := parser.arguments()
-	parser.consume(PARANRIGHT, "Expected ')' and end of function call")
+	if parser.consume(PARANRIGHT, "Expected ')' and end of function call") {
+		parser.addCall(callee, len(arguments))
+	}
 	if len(arguments) > 255 {
-		parser.addError("Can't have more than 255 arguments", ERROR_RESOLVER)
+		parser.addErrorAt("Can't have more than 255 arguments", paren, parser.peekPrevious(), ERROR_RESOLVER, "")
+	}
+	return &Call{Span: parser.spanAfter(callee), Callee: callee, Argument: arguments, Paren: parser.peekPrevious()}
+}
+
+// resolveProperties links properties on this and super to the method found along the inheritance chain,
+// properties of other objects are only offered every method with that name as a definition
+func (parser *Parser) resolveProperties() {
+	for _, property := range parser.properties {
+		name, ok := property.property.Value.(string)
+		if !ok {
+			continue
+		}
+
+		definitions := make([]Token, 0)
+		resolved := false
+		switch node := property.node.(type) {
+		case *Super:
+			resolved = true
+			if superclass, ok := parser.superclasses[property.class]; ok {
+				if method, found := parser.findMethod(superclass, name); found {
+					definitions = append(definitions, method)
+				}
+			}
+			node.Definitions = definitions
+		case *GetExpr:
+			if _, isThis := node.Object.(*This); isThis && property.class != (Token{}) {
+				resolved = true
+				if method, found := parser.findMethod(property.class, name); found {
+					definitions = append(definitions, method)
+				}
+			} else {
+				for _, methods := range parser.methods {
+					if method, found := methods[name]; found {
+						definitions = append(definitions, method)
+					}
+				}
+				slices.SortFunc(definitions, func(a Token, b Token) int {
+					if a.Line != b.Line {
+						return a.Line - b.Line
+					}
+					return a.Character - b.Character
+				})
+			}
+			node.Definitions = definitions
+		}
+
+		if len(definitions) > 0 {
+			parser.identifierNodes = append(parser.identifierNodes, property.node)
+		}
+		// the object may be of a class declaring a field by that name, renaming a candidate must not touch the access
+		if !resolved {
+			for _, definition := range definitions {
+				parser.ambiguousUses[definition] = true
+			}
+			continue
+		}
+		for _, definition := range definitions {
+			parser.references[definition] = append(parser.references[definition], property.property)
+		}
+	}
+}
+
+func (parser *Parser) findMethod(class Token, name string) (Token, bool) {
+	visited := make(map[Token]bool)
+	for ok := true; ok && !visited[class]; class, ok = parser.superclasses[class] {
+		visited[class] = true
+		if method, found := parser.methods[class][name]; found {
+			return method, true
+		}
+	}
+	return Token{}, false
+}
+
+// addCall records calls to plain identifiers, the only callees that can be resolved statically
+func (parser *Parser) addCall(callee Node, arguments int) {
+	variable, ok := callee.(*Variable)
+	if !ok {
+		return
+	}
+	parser.calls = append(parser.calls, pendingCall{callee: *variable, arguments: arguments, span: parser.spanAfter(callee)})
+}
+
+func (parser *Parser) checkArities() {
+	for _, call := range parser.calls {
+		arity, known := parser.arities[call.callee.Definition]
+		if name, ok := call.callee.Identifier.Value.(string); ok && slices.Contains(NativeFunctions, name) {
+			arity, known = nativeArities[name]
+		}
+		if !known || arity == call.arguments {
+			continue
+		}
+		arityError := spanError(fmt.Sprintf("Expected %d arguments but got %d", arity, call.arguments), call.span, 1, ERROR_RESOLVER)
+		arityError.Code = CODE_ARITY_MISMATCH
+		parser.errorList = append(parser.errorList, arityError)
 	}
-	return &Call{Callee: callee, Argument: arguments}
 }
 
 func (parser *Parser) arguments() []Node {
@@ -568,49 +800,56 @@ func (parser *Parser) primary() Node {
 
 	switch {
 	case parser.match(STRING):
-		return &Primary{ValType: "string", Value: currToken.Value}
+		return &Primary{Span: parser.spanFrom(currToken), ValType: "string", Value: currToken.Value}
 	case parser.match(NUMBER):
-		return &Primary{ValType: "number", Value: currToken.Value}
+		return &Primary{Span: parser.spanFrom(currToken), ValType: "number", Value: currToken.Value}
 	case parser.match(TRUE):
-		return &Primary{ValType: "boolean", Value: true}
+		return &Primary{Span: parser.spanFrom(currToken), ValType: "boolean", Value: true}
 	case parser.match(FALSE):
-		return &Primary{ValType: "boolean", Value: false}
+		return &Primary{Span: parser.spanFrom(currToken), ValType: "boolean", Value: false}
 	case parser.match(NIL):
-		return &Primary{ValType: "nil", Value: nil}
+		return &Primary{Span: parser.spanFrom(currToken), ValType: "nil", Value: nil}
 	case parser.match(THIS):
 		if parser.symbolMap.classContext != CLASS_CONTEXT {
-			parser.addError("Invalid use of 'this' keyword outside of class context ", ERROR_RESOLVER)
+			parser.addErrorAt("Invalid use of 'this' keyword outside of class context ", currToken, currToken, ERROR_RESOLVER, "")
 		}
-		return &This{Identifier: currToken}
+		return &This{Span: parser.spanFrom(currToken), Identifier: currToken}
 	case parser.match(SUPER):
 		if parser.symbolMap.classContext != CLASS_CONTEXT {
-			parser.addError("Invalid use of 'super' keyword outside of class context ", ERROR_RESOLVER)
+			parser.addErrorAt("Invalid use of 'super' keyword outside of class context ", currToken, currToken, ERROR_RESOLVER, "")
+		}
+		if parser.symbolMap.classContext == CLASS_CONTEXT && !parser.hasSuperclass {
+			parser.addErrorAt("Invalid use of 'super' in a class with no superclass", currToken, currToken, ERROR_RESOLVER, "")
 		}
 		parser.consume(DOT, "Expected '.' after super")
-		parser.consume(IDENTIFIER, "Expected method name for super-class")
-		return &Super{Identifier: currToken, Property: parser.peekPrevious()}
+		if !parser.consume(IDENTIFIER, "Expected method name for super-class") {
+			return &Super{Span: parser.spanFrom(currToken), Identifier: currToken, Property: parser.peekPrevious()}
+		}
+		super := &Super{Span: parser.spanFrom(currToken), Identifier: currToken, Property: parser.peekPrevious()}
+		parser.properties = append(parser.properties, pendingProperty{node: super, property: super.Property, class: parser.currentClass})
+		return super
 	case parser.match(IDENTIFIER):
 		name, ok := currToken.Value.(string)
 		var definition Token
 		if ok {
 			if slices.Contains(NativeFunctions, name) {
-				return &Variable{Identifier: currToken}
+				return &Variable{Span: parser.spanFrom(currToken), Identifier: currToken}
 			}
 			definition, ok = parser.getDefinition(name)
-			result := Variable{Identifier: currToken, Definition: definition}
+			result := Variable{Span: parser.spanFrom(currToken), Identifier: currToken, Definition: definition}
 			if !ok {
-				parser.addError(fmt.Sprintf("%s is not defined in current scope", name), ERROR_RESOLVER)
+				parser.addErrorAt(fmt.Sprintf("%s is not defined in current scope", name), currToken, currToken, ERROR_RESOLVER, CODE_UNDEFINED_VARIABLE)
 			} else {
 				parser.addIdentifier(&result, definition, currToken)
 			}
 			return &result
 		} else {
-			return &Variable{Identifier: currToken}
+			return &Variable{Span: parser.spanFrom(currToken), Identifier: currToken}
 		}
 	case parser.match(PARANLEFT):
 		expr := parser.expression()
 		parser.consume(PARANRIGHT, fmt.Sprintf("Expected ')' at line %d character %d", currToken.Line+1, currToken.Character+1))
-		return &Group{Expression: expr}
+		return &Group{Span: parser.spanFrom(currToken), Expression: expr}
 
 	case parser.peekParser().TokenType == (EOF):
 		parser.addError("Unexpected end of file", ERROR_PARSER)
@@ -650,40 +889,96 @@ func (parser *Parser) consume(tokenType int, message string) bool {
 	if parser.match(tokenType) {
 		return true
 	}
+	if tokenType == SEMICOLON {
+		// point at the end of the statement where the semicolon belongs
+		token := parser.lastToken()
+		parser.addErrorAt(message, token, token, ERROR_PARSER, CODE_MISSING_SEMICOLON)
+		return false
+	}
 	parser.addError(message, ERROR_PARSER)
 	return false
 }
 
 func (parser *Parser) addError(message string, source int) {
-	if parser.panicMode {
-		return
-	}
-	parser.errorList = append(parser.errorList, CompileError{Message: message, Line: parser.peekParser().Line, Char: parser.peekParser().Character, Severity: 1, Source: source})
-	parser.panicMode = true
+	token := parser.offendingToken()
+	parser.addErrorAt(message, token, token, source, "")
 }
 
 func (parser *Parser) addWarning(message string) {
-	if parser.panicMode {
-		return
-	}
-	parser.errorList = append(parser.errorList, CompileError{Message: message, Line: parser.peekParser().Line, Char: parser.peekParser().Character, Severity: 2, Source: ERROR_WARNING})
+	token := parser.offendingToken()
+	parser.addWarningAt(message, token, "")
 }
 
-func (parser *Parser) addWarningAt(message string, line int, char int) {
+func (parser *Parser) addWarningAt(message string, token Token, code string) {
 	if parser.panicMode {
 		return
 	}
-	parser.errorList = append(parser.errorList, CompileError{Message: message, Line: line, Char: char, Severity: 2, Source: ERROR_WARNING})
+	warning := spanError(message, parser.tokenSpan(token, token), 2, ERROR_WARNING)
+	warning.Code = code
+	parser.errorList = append(parser.errorList, warning)
 }
 
-func (parser *Parser) addErrorAt(message string, line int, char int, source int) {
+// addErrorAt reports an error spanning from the start of the start token to the end of the end token
+func (parser *Parser) addErrorAt(message string, start Token, end Token, source int, code string) {
+	parser.addErrorSpan(message, parser.tokenSpan(start, end), source, code)
+}
+
+// addErrorSpan reports an error over the source of a node
+func (parser *Parser) addErrorSpan(message string, span Span, source int, code string) {
 	if parser.panicMode {
 		return
 	}
-	parser.errorList = append(parser.errorList, CompileError{Message: message, Line: line, Char: char, Severity: 1, Source: source})
+	compileError := spanError(message, span, 1, source)
+	compileError.Code = code
+	parser.errorList = append(parser.errorList, compileError)
 	parser.panicMode = true
 }
 
+// offendingToken is the token an error at the current position points at,
+// at a line break, comment or the end of file that is the last token before it
+func (parser *Parser) offendingToken() Token {
+	token := parser.peekParser()
+	if token.TokenType != NEWLINE && token.TokenType != COMMENT && token.TokenType != EOF {
+		return token
+	}
+	return parser.lastToken()
+}
+
+// spanFrom covers the source from the start token to the last consumed token
+func (parser *Parser) spanFrom(start Token) Span {
+	return parser.tokenSpan(start, parser.lastToken())
+}
+
+// spanAfter extends the span of a node over the tokens consumed since
+func (parser *Parser) spanAfter(node Node) Span {
+	return Span{Start: node.GetSpan().Start, End: parser.tokenEnd(parser.lastToken())}
+}
+
+// tokenEnd keeps the end of a token inside the source, an unterminated string spanning
+// several lines has no closing quote to end after
+func (parser *Parser) tokenEnd(token Token) Position {
+	end := tokenEnd(token)
+	if eof := parser.tokenList[len(parser.tokenList)-1]; end.Offset > eof.Offset {
+		return tokenStart(eof)
+	}
+	return end
+}
+
+func (parser *Parser) tokenSpan(start Token, end Token) Span {
+	return Span{Start: tokenStart(start), End: parser.tokenEnd(end)}
+}
+
+// lastToken is the last consumed token that is not a line break or comment
+func (parser *Parser) lastToken() Token {
+	for i := parser.currentToken - 1; i >= 0; i-- {
+		previous := parser.tokenList[i]
+		if previous.TokenType != NEWLINE && previous.TokenType != COMMENT {
+			return previous
+		}
+	}
+	return parser.peekParser()
+}
+
 func (parser *Parser) peekPrevious() Token {
 	return parser.tokenList[parser.currentToken-1]
 }
@@ -0,0 +1,190 @@
+package lox
+
+import (
+	"fmt"
+	"time"
+)
+
+/* values the interpreter works with besides nil, bool, float64 and string */
+
+type RuntimeError struct {
+	Message string
+	Token   Token
+}
+
+func (runtimeError RuntimeError) Error() string {
+	return fmt.Sprintf("[line %d] Runtime error: %s", runtimeError.Token.Line+1, runtimeError.Message)
+}
+
+// unwinds the call stack of the interpreter up to the enclosing function call
+type returnValue struct {
+	value any
+}
+
+type binding struct {
+	value       any
+	declaration Token // token the resolver linked the uses of this name to
+}
+
+type environment struct {
+	values    map[string]binding
+	enclosing *environment
+}
+
+func newEnvironment(enclosing *environment) *environment {
+	return &environment{values: make(map[string]binding), enclosing: enclosing}
+}
+
+func (env *environment) define(name string, declaration Token, value any) {
+	env.values[name] = binding{value: value, declaration: declaration}
+}
+
+// find returns the environment holding the binding the resolver picked for a use of name,
+// a zero declaration matches any binding of the name
+func (env *environment) find(name string, declaration Token) *environment {
+	for current := env; current != nil; current = current.enclosing {
+		value, ok := current.values[name]
+		if !ok {
+			continue
+		}
+		// globals can be redeclared, so their declaration may not match the resolved one
+		if declaration == (Token{}) || value.declaration == declaration || current.enclosing == nil {
+			return current
+		}
+	}
+	return nil
+}
+
+type callable interface {
+	arity() int
+	call(interpreter *Interpreter, arguments []any) any
+}
+
+type nativeFunction struct {
+	name       string
+	parameters int
+	function   func(arguments []any) any
+}
+
+func (native *nativeFunction) arity() int { return native.parameters }
+
+func (native *nativeFunction) call(_ *Interpreter, arguments []any) any {
+	return native.function(arguments)
+}
+
+func (native *nativeFunction) String() string { return "<native fn>" }
+
+var natives = []*nativeFunction{
+	{name: "clock", parameters: 0, function: func([]any) any {
+		return float64(time.Now().UnixNano()) / float64(time.Second)
+	}},
+}
+
+type loxFunction struct {
+	declaration   *FuncDecl
+	closure       *environment
+	isInitializer bool
+}
+
+func (function *loxFunction) arity() int { return len(function.declaration.Parameters) }
+
+func (function *loxFunction) call(interpreter *Interpreter, arguments []any) (result any) {
+	env := newEnvironment(function.closure)
+	for i, parameter := range function.declaration.Parameters {
+		identifier := parameter.(*Variable).Identifier
+		name, _ := identifier.Value.(string)
+		env.define(name, identifier, arguments[i])
+	}
+
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+		returned, ok := recovered.(returnValue)
+		if !ok {
+			panic(recovered)
+		}
+		result = returned.value
+		if function.isInitializer {
+			result = function.this()
+		}
+	}()
+
+	interpreter.executeBlock(function.declaration.Body.(*BlockStmt).Body, env)
+	if function.isInitializer {
+		return function.this()
+	}
+	return nil
+}
+
+func (function *loxFunction) this() any {
+	return function.closure.values["this"].value
+}
+
+func (function *loxFunction) bind(instance *loxInstance) *loxFunction {
+	env := newEnvironment(function.closure)
+	env.define("this", Token{}, instance)
+	return &loxFunction{declaration: function.declaration, closure: env, isInitializer: function.isInitializer}
+}
+
+func (function *loxFunction) String() string {
+	return fmt.Sprintf("<fn %v>", function.declaration.Name.Value)
+}
+
+type loxClass struct {
+	name       string
+	superclass *loxClass
+	methods    map[string]*loxFunction
+}
+
+func (class *loxClass) findMethod(name string) *loxFunction {
+	for current := class; current != nil; current = current.superclass {
+		if method, ok := current.methods[name]; ok {
+			return method
+		}
+	}
+	return nil
+}
+
+func (class *loxClass) arity() int {
+	if initializer := class.findMethod("init"); initializer != nil {
+		return initializer.arity()
+	}
+	return 0
+}
+
+func (class *loxClass) call(interpreter *Interpreter, arguments []any) any {
+	instance := &loxInstance{class: class, fields: make(map[string]any)}
+	if initializer := class.findMethod("init"); initializer != nil {
+		initializer.bind(instance).call(interpreter, arguments)
+	}
+	return instance
+}
+
+func (class *loxClass) String() string { return class.name }
+
+type loxInstance struct {
+	class  *loxClass
+	fields map[string]any
+}
+
+func (instance *loxInstance) get(property Token) any {
+	name, _ := property.Value.(string)
+	if value, ok := instance.fields[name]; ok {
+		return value
+	}
+	if method := instance.class.findMethod(name); method != nil {
+		return method.bind(instance)
+	}
+	panic(RuntimeError{Message: fmt.Sprintf("Undefined property '%s'.", name), Token: property})
+}
+
+func (instance *loxInstance) set(property Token, value any) {
+	name, _ := property.Value.(string)
+	instance.fields[name] = value
+}
+
+func (instance *loxInstance) String() string {
+	return instance.class.name + " instance"
+}
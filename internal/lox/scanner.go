@@ -3,6 +3,7 @@ package lox
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -12,6 +13,7 @@ type Token struct {
 	Value     any
 	Character int
 	Length    int
+	Offset    int // byte offset of the first character, a line break points at the start of the line it opens like Line and Character do
 }
 
 type Scanner struct {
@@ -38,12 +40,20 @@ func (scannerState *Scanner) Scan(code string) ([]Token, []CompileError, error)
 	scannerState.initializeScanner(&code)
 
 	for len(*scannerState.source) > scannerState.current {
+		start, scanned := scannerState.current, len(scannerState.tokens)
 		err := scannerState.scanToken()
 		if err != nil {
 			return scannerState.tokens, scannerState.lexicalErrors, err
 		}
+		// every call scans at most one token, starting where the call started
+		for i := scanned; i < len(scannerState.tokens); i++ {
+			scannerState.tokens[i].Offset = start
+			if scannerState.tokens[i].TokenType == NEWLINE {
+				scannerState.tokens[i].Offset = scannerState.current
+			}
+		}
 	}
-	scannerState.tokens = append(scannerState.tokens, Token{TokenType: EOF, Line: scannerState.line, Character: scannerState.currChar})
+	scannerState.tokens = append(scannerState.tokens, Token{TokenType: EOF, Line: scannerState.line, Character: scannerState.currChar, Offset: scannerState.current})
 
 	return scannerState.tokens, scannerState.lexicalErrors, nil
 }
@@ -67,6 +77,28 @@ var keywords map[string]int = map[string]int{
 	"return": RETURN,
 }
 
+// IsIdentifier reports whether name would be scanned as a single identifier token
+func IsIdentifier(name string) bool {
+	if _, isKeyword := keywords[name]; isKeyword || name == "" {
+		return false
+	}
+	for i, char := range name {
+		if i == 0 && !unicode.IsLetter(char) {
+			return false
+		}
+		if !unicode.IsLetter(char) && !unicode.IsDigit(char) && char != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsKeyword reports whether name is a reserved word of the language
+func IsKeyword(name string) bool {
+	_, isKeyword := keywords[name]
+	return isKeyword
+}
+
 func (scannerState *Scanner) scanNumber(char rune) (bool, error) {
 	if !unicode.IsDigit(char) {
 		return false, nil
@@ -165,7 +197,7 @@ func (scannerState *Scanner) scanToken() error {
 		if scannerState.matchScanner('/') {
 			start := scannerState.current
 			startChar := scannerState.currChar - 2
-			for scannerState.peekScannerNext() != '\n' && len(*scannerState.source) > scannerState.current {
+			for len(*scannerState.source) > scannerState.current && scannerState.peekScanner() != '\n' {
 				scannerState.advanceScanner()
 			}
 			scannerState.tokens = append(scannerState.tokens,
@@ -173,7 +205,7 @@ func (scannerState *Scanner) scanToken() error {
 					TokenType: COMMENT,
 					Line:      scannerState.line,
 					Character: startChar,
-					Value:     fmt.Sprintf("%s", (*scannerState.source)[start:scannerState.current]),
+					Value:     strings.TrimSuffix((*scannerState.source)[start:scannerState.current], "\r"),
 					Length:    scannerState.currChar - startChar,
 				})
 			return nil
@@ -207,15 +239,22 @@ func (scannerState *Scanner) scanToken() error {
 		start := scannerState.current
 		startChar := scannerState.currChar - 1
 		startLine := scannerState.line
-		for len(*scannerState.source)-1 > scannerState.current && scannerState.peekScanner() != '"' {
-			if scannerState.peekScanner() == '\n' {
+		for len(*scannerState.source) > scannerState.current && scannerState.peekScanner() != '"' {
+			newLine := scannerState.peekScanner() == '\n'
+			scannerState.advanceScanner()
+			if newLine {
 				scannerState.line++
 				scannerState.currChar = 0
 			}
-			scannerState.advanceScanner()
 		}
-		scannerState.consumeScanner('"', fmt.Sprintf("Expected \" at end of string at line %d column %d", scannerState.line, scannerState.currChar))
-		scannerState.tokens = append(scannerState.tokens, Token{TokenType: STRING, Line: startLine, Character: startChar, Value: (*scannerState.source)[start : scannerState.current-1]})
+		value := (*scannerState.source)[start:scannerState.current]
+		quote := Token{Line: startLine, Character: startChar, Length: 1, Offset: start - 1}
+		scannerState.consumeScanner('"', fmt.Sprintf("Expected \" at end of string starting at line %d column %d", startLine+1, startChar+1), quote)
+		token := Token{TokenType: STRING, Line: startLine, Character: startChar, Value: value}
+		if scannerState.line == startLine {
+			token.Length = scannerState.currChar - startChar
+		}
+		scannerState.tokens = append(scannerState.tokens, token)
 
 	default:
 		isKeyword, err := scannerState.scanKeywords(char)
@@ -225,7 +264,8 @@ func (scannerState *Scanner) scanToken() error {
 			}
 			return nil
 		}
-		scannerState.lexicalErrors = append(scannerState.lexicalErrors, CompileError{Line: scannerState.line, Char: scannerState.currChar, Message: fmt.Sprintf("Unexpected character %c at line %d column %d", char, scannerState.line+1, scannerState.currChar+1), Severity: 1, Source: ERROR_SCANNER})
+		token := Token{Line: scannerState.line, Character: scannerState.currChar - 1, Length: 1, Offset: scannerState.current - 1}
+		scannerState.lexicalErrors = append(scannerState.lexicalErrors, spanError(fmt.Sprintf("Unexpected character %c at line %d column %d", char, token.Line+1, token.Character+1), tokenSpan(token, token), 1, ERROR_SCANNER))
 		return nil
 	}
 	return nil
@@ -246,16 +286,18 @@ func (scannerState *Scanner) peekScannerNext() rune {
 }
 
 func (scannerState *Scanner) matchScanner(char rune) bool {
-	if (*scannerState.source)[scannerState.current] == byte(char) {
+	if len(*scannerState.source) > scannerState.current && (*scannerState.source)[scannerState.current] == byte(char) {
 		scannerState.advanceScanner()
 		return true
 	}
 	return false
 }
 
-func (scannerState *Scanner) consumeScanner(char rune, err string) {
+// consumeScanner reports an error spanning from start to the current position if char is missing
+func (scannerState *Scanner) consumeScanner(char rune, err string, start Token) {
 	if scannerState.matchScanner(char) {
 		return
 	}
-	scannerState.lexicalErrors = append(scannerState.lexicalErrors, CompileError{Line: scannerState.line + 1, Char: scannerState.currChar + 1, Message: fmt.Sprintf("%s", err), Severity: 1, Source: ERROR_SCANNER})
+	end := Token{Line: scannerState.line, Character: scannerState.currChar, Offset: scannerState.current}
+	scannerState.lexicalErrors = append(scannerState.lexicalErrors, spanError(err, tokenSpan(start, end), 1, ERROR_SCANNER))
 }
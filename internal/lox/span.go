@@ -0,0 +1,45 @@
+package lox
+
+import "strings"
+
+// Position is 0-based, Offset counts bytes from the start of the source
+type Position struct {
+	Line      int
+	Character int
+	Offset    int
+}
+
+// Span is the source text of a node, End points just past its last character
+type Span struct {
+	Start Position
+	End   Position
+}
+
+func (span Span) GetSpan() Span { return span }
+
+func (span Span) Contains(position Position) bool {
+	return !positionBefore(position, span.Start) && !positionBefore(span.End, position)
+}
+
+func (span Span) IsZero() bool { return span == Span{} }
+
+func positionBefore(position Position, other Position) bool {
+	return position.Line < other.Line || (position.Line == other.Line && position.Character < other.Character)
+}
+
+func tokenStart(token Token) Position {
+	return Position{Line: token.Line, Character: token.Character, Offset: token.Offset}
+}
+
+// tokenEnd accounts for strings spanning several lines, whose tokens carry no length
+func tokenEnd(token Token) Position {
+	if value, ok := token.Value.(string); ok && token.TokenType == STRING && strings.Contains(value, "\n") {
+		lines := strings.Split(value, "\n")
+		return Position{Line: token.Line + len(lines) - 1, Character: len(lines[len(lines)-1]) + 1, Offset: token.Offset + len(value) + 2}
+	}
+	return Position{Line: token.Line, Character: token.Character + token.Length, Offset: token.Offset + token.Length}
+}
+
+func tokenSpan(start Token, end Token) Span {
+	return Span{Start: tokenStart(start), End: tokenEnd(end)}
+}
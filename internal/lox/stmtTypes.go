@@ -1,6 +1,7 @@
 package lox
 
 type ExpressionStmt struct {
+	Span
 	Expr Node
 }
 
@@ -9,6 +10,7 @@ func (expr *ExpressionStmt) Accept(visitor Visitor) {
 }
 
 type PrintStmt struct {
+	Span
 	Expr Node
 }
 
@@ -17,6 +19,7 @@ func (expr *PrintStmt) Accept(visitor Visitor) {
 }
 
 type ReturnStmt struct {
+	Span
 	Expr         Node
 	ReturnsValue bool
 }
@@ -26,6 +29,7 @@ func (expr *ReturnStmt) Accept(visitor Visitor) {
 }
 
 type BlockStmt struct {
+	Span
 	Body         []Node
 	BlockContext int
 }
@@ -35,6 +39,7 @@ func (expr *BlockStmt) Accept(visitor Visitor) {
 }
 
 type IfStmt struct {
+	Span
 	Condition Node
 	Then      Node
 	Else      Node
@@ -45,6 +50,7 @@ func (expr *IfStmt) Accept(visitor Visitor) {
 }
 
 type VarDecl struct {
+	Span
 	Identifier  Token
 	Value       Node
 	Initialized bool
@@ -55,6 +61,7 @@ func (expr *VarDecl) Accept(visitor Visitor) {
 }
 
 type ForStmt struct {
+	Span
 	Initializer Node
 	Condition   Node
 	Assignment  Node
@@ -66,6 +73,7 @@ func (expr *ForStmt) Accept(visitor Visitor) {
 }
 
 type WhileStmt struct {
+	Span
 	Condition Node
 	Then      Node
 }
@@ -75,6 +83,7 @@ func (expr *WhileStmt) Accept(visitor Visitor) {
 }
 
 type FuncDecl struct {
+	Span
 	Name         Token
 	Body         Node
 	Parameters   []Node
@@ -86,6 +95,7 @@ func (expr *FuncDecl) Accept(visitor Visitor) {
 }
 
 type ClassDecl struct {
+	Span
 	Name   Token
 	Parent *Token
 	Body   []Node
@@ -96,6 +106,7 @@ func (expr *ClassDecl) Accept(visitor Visitor) {
 }
 
 type NewLine struct {
+	Span
 	Token Token
 }
 
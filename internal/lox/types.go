@@ -9,8 +9,23 @@ type CompileError struct {
 	Message  string
 	Line     int
 	Char     int
+	EndLine  int
+	EndChar  int
 	Severity int
 	Source   int
+	Code     string
+}
+
+func spanError(message string, span Span, severity int, source int) CompileError {
+	return CompileError{
+		Message:  message,
+		Line:     span.Start.Line,
+		Char:     span.Start.Character,
+		EndLine:  span.End.Line,
+		EndChar:  span.End.Character,
+		Severity: severity,
+		Source:   source,
+	}
 }
 
 const (
@@ -20,3 +35,12 @@ const (
 	ERROR_WARNING
 	ERROR_NONE
 )
+
+// stable diagnostic codes, code actions match on these rather than on messages
+const (
+	CODE_MISSING_SEMICOLON     = "missing-semicolon"
+	CODE_UNUSED_DEFINITION     = "unused-definition"
+	CODE_UNDEFINED_VARIABLE    = "undefined-variable"
+	CODE_DUPLICATE_DECLARATION = "duplicate-declaration"
+	CODE_ARITY_MISMATCH        = "arity-mismatch"
+)
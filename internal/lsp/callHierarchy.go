@@ -0,0 +1,131 @@
+package lsp
+
+import (
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+	"slices"
+	"strings"
+)
+
+/* callers and callees of functions, methods and constructors within a document */
+
+// top level code calling into functions is shown as a caller of this name
+const scriptCaller = "<script>"
+
+func (loxService *DocumentService) PrepareCallHierarchy(position lsp.Position) []lsp.CallHierarchyItem {
+	var definitions []lsp.Position
+	if definition, _, found := loxService.getSymbol(position); found {
+		definitions = tokenPositions([]lox.Token{definition})
+	} else if positions, found := loxService.GetDefinition(position); found {
+		definitions = positions
+	}
+
+	items := make([]lsp.CallHierarchyItem, 0)
+	for _, declaration := range lox.Declarations(loxService.AST) {
+		isCallable := declaration.Kind == lox.DECLARATION_FUNCTION || declaration.Kind == lox.DECLARATION_METHOD ||
+			declaration.Kind == lox.DECLARATION_CLASS
+		if isCallable && slices.Contains(definitions, tokenStart(declaration.Name)) {
+			items = append(items, loxService.callHierarchyItem(declaration))
+		}
+	}
+	return items
+}
+
+func (loxService *DocumentService) IncomingCalls(item lsp.CallHierarchyItem) []lsp.CallHierarchyIncomingCall {
+	declarations := lox.Declarations(loxService.AST)
+	calls := make([]lsp.CallHierarchyIncomingCall, 0)
+	callers := make(map[*lox.FuncDecl]int)
+
+	for _, site := range lox.CallSites(loxService.AST) {
+		if !slices.ContainsFunc(site.Callees, func(callee lox.Token) bool {
+			return loxService.lines.tokenRange(callee) == item.SelectionRange
+		}) {
+			continue
+		}
+		index, ok := callers[site.Caller]
+		if !ok {
+			from := loxService.scriptItem()
+			if site.Caller != nil {
+				if declaration := findDeclaration(declarations, site.Caller.Name); declaration != nil {
+					from = loxService.callHierarchyItem(declaration)
+				}
+			}
+			index = len(calls)
+			callers[site.Caller] = index
+			calls = append(calls, lsp.CallHierarchyIncomingCall{From: from, FromRanges: make([]lsp.Range, 0)})
+		}
+		calls[index].FromRanges = append(calls[index].FromRanges, loxService.lines.tokenRange(site.Site))
+	}
+	return calls
+}
+
+func (loxService *DocumentService) OutgoingCalls(item lsp.CallHierarchyItem) []lsp.CallHierarchyOutgoingCall {
+	declarations := lox.Declarations(loxService.AST)
+	var caller *lox.FuncDecl
+	if item.Name != scriptCaller || item.Kind != lsp.SymbolKindFile {
+		for _, declaration := range declarations {
+			function, ok := declaration.Node.(*lox.FuncDecl)
+			if ok && loxService.lines.tokenRange(declaration.Name) == item.SelectionRange {
+				caller = function
+			}
+		}
+		if caller == nil {
+			return nil
+		}
+	}
+
+	calls := make([]lsp.CallHierarchyOutgoingCall, 0)
+	callees := make(map[lox.Token]int)
+	for _, site := range lox.CallSites(loxService.AST) {
+		if site.Caller != caller {
+			continue
+		}
+		for _, callee := range site.Callees {
+			index, ok := callees[callee]
+			if !ok {
+				declaration := findDeclaration(declarations, callee)
+				if declaration == nil {
+					continue
+				}
+				index = len(calls)
+				callees[callee] = index
+				calls = append(calls, lsp.CallHierarchyOutgoingCall{To: loxService.callHierarchyItem(declaration), FromRanges: make([]lsp.Range, 0)})
+			}
+			calls[index].FromRanges = append(calls[index].FromRanges, loxService.lines.tokenRange(site.Site))
+		}
+	}
+	return calls
+}
+
+func (loxService *DocumentService) callHierarchyItem(declaration *lox.Declaration) lsp.CallHierarchyItem {
+	name, _ := declaration.Name.Value.(string)
+	return lsp.CallHierarchyItem{
+		Name:           name,
+		Kind:           symbolKind(declaration),
+		Detail:         declaration.Signature(),
+		Uri:            loxService.Uri,
+		Range:          declarationRange(loxService.lines, declaration),
+		SelectionRange: loxService.lines.tokenRange(declaration.Name),
+	}
+}
+
+// scriptItem stands for the top level code, spanning the whole document
+func (loxService *DocumentService) scriptItem() lsp.CallHierarchyItem {
+	start := lsp.Position{Line: 0, Character: 0}
+	return lsp.CallHierarchyItem{
+		Name:           scriptCaller,
+		Kind:           lsp.SymbolKindFile,
+		Uri:            loxService.Uri,
+		Range:          lsp.Range{Start: start, End: lsp.Position{Line: uint(strings.Count(loxService.Text, "\n")), Character: 0}},
+		SelectionRange: lsp.Range{Start: start, End: start},
+	}
+}
+
+func findDeclaration(declarations []*lox.Declaration, name lox.Token) *lox.Declaration {
+	for _, declaration := range declarations {
+		if declaration.Name == name {
+			return declaration
+		}
+	}
+	return nil
+}
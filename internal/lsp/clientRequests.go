@@ -0,0 +1,109 @@
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	lsp "lox-server/internal/lsp/types"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/* requests sent to the client, answers are matched back to them by id */
+
+// clientRequestTimeout bounds the wait for an answer, a client may never answer some requests
+const clientRequestTimeout = 30 * time.Second
+
+var errClientTimeout = errors.New("client did not answer in time")
+
+// clientCallback receives the result of a request sent to the client, or why there is none
+type clientCallback func(result json.RawMessage, err error)
+
+type pendingRequests struct {
+	mutex   sync.Mutex
+	nextId  int
+	waiting map[int]*pendingRequest
+}
+
+type pendingRequest struct {
+	callback clientCallback
+	timer    *time.Timer
+}
+
+func (pending *pendingRequests) Initialize() {
+	defer pending.mutex.Unlock()
+	pending.mutex.Lock()
+	pending.nextId = 1
+	pending.waiting = make(map[int]*pendingRequest)
+}
+
+// add registers a request before it is written, so an answer arriving right away finds it
+func (pending *pendingRequests) add(callback clientCallback, timeout time.Duration) int {
+	defer pending.mutex.Unlock()
+	pending.mutex.Lock()
+	id := pending.nextId
+	pending.nextId++
+	request := &pendingRequest{callback: callback}
+	request.timer = time.AfterFunc(timeout, func() {
+		if pending.finish(id, nil, errClientTimeout) {
+			sendCancelRequest(id)
+		}
+	})
+	pending.waiting[id] = request
+	return id
+}
+
+// finish resolves a request once, false when it was already answered or timed out
+func (pending *pendingRequests) finish(id int, result json.RawMessage, err error) bool {
+	pending.mutex.Lock()
+	request, ok := pending.waiting[id]
+	delete(pending.waiting, id)
+	pending.mutex.Unlock()
+	if !ok {
+		return false
+	}
+
+	request.timer.Stop()
+	if request.callback != nil {
+		// callbacks may send further requests, the reading loop delivering their answers must not wait on them
+		go request.callback(result, err)
+	}
+	return true
+}
+
+// resolve hands a response read from the client to the request waiting for it
+func (pending *pendingRequests) resolve(response lsp.ClientResponse) {
+	id, ok := responseId(response.Id)
+	if !ok {
+		return
+	}
+	if response.Error != nil {
+		pending.finish(id, nil, response.Error)
+		return
+	}
+	pending.finish(id, response.Result, nil)
+}
+
+// responseId reads back an id the server allocated, numbers decode as float64
+func responseId(id any) (int, bool) {
+	switch id := id.(type) {
+	case float64:
+		return int(id), id == float64(int(id))
+	case string:
+		number, err := strconv.Atoi(id)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+func sendCancelRequest(id int) {
+	notification, err := json.Marshal(lsp.JsonRpcNotification{
+		JsonRpc: "2.0",
+		Method:  "$/cancelRequest",
+		Params:  lsp.CancelParams{Id: id},
+	})
+	if err != nil {
+		return
+	}
+	sendNotification(notification)
+}
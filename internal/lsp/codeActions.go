@@ -0,0 +1,217 @@
+package lsp
+
+import (
+	"fmt"
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+	"strings"
+)
+
+/* quick fixes for the diagnostics reported by the parser, matched on their codes */
+
+func (loxService *DocumentService) GetCodeActions(diagnostics []lsp.Diagnostic) []lsp.CodeAction {
+	actions := make([]lsp.CodeAction, 0)
+	for _, diagnostic := range diagnostics {
+		switch diagnostic.Code {
+		case lox.CODE_MISSING_SEMICOLON:
+			end := diagnostic.ErrRange.End
+			actions = append(actions, loxService.quickFix("Insert missing ;", diagnostic, true, lsp.TextEdit{
+				Range:   lsp.Range{Start: end, End: end},
+				NewText: ";",
+			}))
+
+		case lox.CODE_UNUSED_DEFINITION:
+			declaration := loxService.declarationAt(loxService.bytePosition(diagnostic.ErrRange.Start))
+			if declaration == nil {
+				continue
+			}
+			name, _ := declaration.Name.Value.(string)
+			// removing a parameter would change the arity of the function
+			if declaration.Kind != lox.DECLARATION_PARAMETER {
+				actions = append(actions, loxService.quickFix(fmt.Sprintf("Remove unused %s", name), diagnostic, false, lsp.TextEdit{
+					Range:   loxService.removalRange(declaration),
+					NewText: "",
+				}))
+			}
+
+		case lox.CODE_UNDEFINED_VARIABLE:
+			name, ok := loxService.GetToken(loxService.bytePosition(diagnostic.ErrRange.Start)).Value.(string)
+			if !ok {
+				continue
+			}
+			firstUse, found := loxService.firstUndefinedUse(name)
+			if !found {
+				continue
+			}
+			actions = append(actions, loxService.quickFix(fmt.Sprintf("Declare variable %s", name), diagnostic, true, loxService.declarationEdit(firstUse, name)))
+
+		case lox.CODE_DUPLICATE_DECLARATION:
+			declaration := loxService.declarationAt(loxService.bytePosition(diagnostic.ErrRange.Start))
+			if declaration == nil {
+				continue
+			}
+			name, _ := declaration.Name.Value.(string)
+			actions = append(actions, loxService.quickFix(fmt.Sprintf("Remove duplicate declaration of %s", name), diagnostic, true, lsp.TextEdit{
+				Range:   loxService.removalRange(declaration),
+				NewText: "",
+			}))
+		}
+	}
+	return actions
+}
+
+func (loxService *DocumentService) quickFix(title string, diagnostic lsp.Diagnostic, preferred bool, edits ...lsp.TextEdit) lsp.CodeAction {
+	return lsp.CodeAction{
+		Title:       title,
+		Kind:        "quickfix",
+		Diagnostics: []lsp.Diagnostic{diagnostic},
+		Edit: lsp.WorkspaceEdit{
+			Changes: map[string][]lsp.TextEdit{
+				loxService.Uri: edits,
+			},
+		},
+		IsPreferred: preferred,
+	}
+}
+
+func (loxService *DocumentService) declarationAt(position lsp.Position) *lox.Declaration {
+	for _, declaration := range lox.Declarations(loxService.AST) {
+		if declaration.Name.Line == int(position.Line) && declaration.Name.Character == int(position.Character) {
+			return declaration
+		}
+	}
+	return nil
+}
+
+// removalRange covers a whole declaration, including its lines when nothing else is written on them
+func (loxService *DocumentService) removalRange(declaration *lox.Declaration) lsp.Range {
+	switch declaration.Node.(type) {
+	case *lox.VarDecl, *lox.FuncDecl, *lox.ClassDecl:
+	case *lox.Variable:
+		return loxService.parameterRemovalRange(declaration)
+	default:
+		return loxService.lines.tokenRange(declaration.Name)
+	}
+
+	span := declaration.Node.GetSpan()
+	removal := loxService.lines.spanRange(span)
+	text := loxService.Source
+	if span.End.Offset > len(text) {
+		return removal
+	}
+	lineStart := strings.LastIndex(text[:span.Start.Offset], "\n") + 1
+	lineEnd := len(text)
+	if newline := strings.Index(text[span.End.Offset:], "\n"); newline >= 0 {
+		lineEnd = span.End.Offset + newline
+	}
+	before := text[lineStart:span.Start.Offset]
+	after := text[span.End.Offset:lineEnd]
+	if strings.TrimSpace(before) == "" && strings.TrimSpace(after) == "" {
+		removal.Start.Character = 0
+		removal.End = lsp.Position{Line: uint(span.End.Line + 1), Character: 0}
+	}
+	return removal
+}
+
+// parameterRemovalRange removes a parameter together with the comma separating it from its neighbour
+func (loxService *DocumentService) parameterRemovalRange(declaration *lox.Declaration) lsp.Range {
+	function, ok := declaration.Parent.Node.(*lox.FuncDecl)
+	if !ok {
+		return loxService.lines.tokenRange(declaration.Name)
+	}
+	for i, parameter := range function.Parameters {
+		if parameter != declaration.Node {
+			continue
+		}
+		current := loxService.lines.tokenRange(declaration.Name)
+		if i > 0 {
+			previous := loxService.lines.tokenRange(function.Parameters[i-1].(*lox.Variable).Identifier)
+			return lsp.Range{Start: previous.End, End: current.End}
+		}
+		if len(function.Parameters) > 1 {
+			next := loxService.lines.tokenRange(function.Parameters[1].(*lox.Variable).Identifier)
+			return lsp.Range{Start: current.Start, End: next.Start}
+		}
+		return current
+	}
+	return loxService.lines.tokenRange(declaration.Name)
+}
+
+func (loxService *DocumentService) firstUndefinedUse(name string) (lox.Token, bool) {
+	undefined := make(map[lsp.Position]bool)
+	for _, compileError := range loxService.Errors {
+		if compileError.Code == lox.CODE_UNDEFINED_VARIABLE {
+			undefined[lsp.Position{Line: uint(compileError.Line), Character: uint(compileError.Char)}] = true
+		}
+	}
+	for _, token := range loxService.Tokens {
+		value, ok := token.Value.(string)
+		if token.TokenType != lox.IDENTIFIER || !ok || value != name {
+			continue
+		}
+		if undefined[lsp.Position{Line: uint(token.Line), Character: uint(token.Character)}] {
+			return token, true
+		}
+	}
+	return lox.Token{}, false
+}
+
+// declarationEdit declares name right before the statement using it, in the innermost block around the use
+func (loxService *DocumentService) declarationEdit(use lox.Token, name string) lsp.TextEdit {
+	statement := loxService.enclosingStatement(use)
+	if statement == nil {
+		start := lsp.Position{Line: uint(use.Line), Character: 0}
+		return lsp.TextEdit{
+			Range:   lsp.Range{Start: start, End: start},
+			NewText: fmt.Sprintf("%svar %s;\n", loxService.indentation(use.Line), name),
+		}
+	}
+
+	start := statement.GetSpan().Start
+	position := loxService.lines.position(start.Line, start.Character)
+	newText := fmt.Sprintf("var %s;\n%s", name, loxService.indentation(start.Line))
+	// a statement sharing its line with code before it gets the declaration on the same line
+	if line := loxService.lines.line(start.Line); start.Character > len(line) || strings.TrimSpace(line[:start.Character]) != "" {
+		newText = fmt.Sprintf("var %s; ", name)
+	}
+	return lsp.TextEdit{Range: lsp.Range{Start: position, End: position}, NewText: newText}
+}
+
+// enclosingStatement finds the innermost statement containing the token that sits in a list of statements,
+// the top level or the body of a block, where a declaration can go before it
+func (loxService *DocumentService) enclosingStatement(token lox.Token) lox.Node {
+	cursor := lox.Position{Line: token.Line, Character: token.Character}
+	contains := func(node lox.Node) bool {
+		span := node.GetSpan()
+		return !span.IsZero() && span.Contains(cursor)
+	}
+
+	var enclosing lox.Node
+	inspectStatements := func(statements []lox.Node) {
+		for _, statement := range statements {
+			if statement != nil && contains(statement) {
+				enclosing = statement
+			}
+		}
+	}
+	inspectStatements(loxService.AST)
+	// the walk is pre-order, so inner blocks replace the statements found in outer ones
+	lox.Inspect(loxService.AST, func(node lox.Node) bool {
+		if !contains(node) {
+			return false
+		}
+		if block, ok := node.(*lox.BlockStmt); ok {
+			inspectStatements(block.Body)
+		}
+		return true
+	})
+	return enclosing
+}
+
+func (loxService *DocumentService) indentation(line int) string {
+	lines := strings.Split(loxService.Source, "\n")
+	if line >= len(lines) {
+		return ""
+	}
+	return lines[line][:len(lines[line])-len(strings.TrimLeft(lines[line], " \t"))]
+}
@@ -1,11 +1,14 @@
 package lsp
 
-import "lox-server/internal/lox"
+import (
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+)
 
 var classContextKeywords []string = []string{
 	//keywords
 	"this",
-	"super.",
+	"super",
 }
 
 var classSnippets []string = []string{
@@ -53,3 +56,107 @@ func getKeywords(scopeContext int, classContext int, functionContext int) []stri
 	return keywords
 
 }
+
+// getMemberCompletion offers the methods and fields of the object before a '.' at the cursor
+func (loxService *DocumentService) getMemberCompletion(position lsp.Position) ([]lsp.CompletionItem, bool) {
+	// the parsed tokens lag behind the buffer while typing, so scan the current text
+	var scanner lox.Scanner
+	tokens, _, err := scanner.Scan(loxService.Text)
+	if err != nil {
+		return nil, false
+	}
+
+	last := -1
+	for i, token := range tokens {
+		if token.Line > int(position.Line) || (token.Line == int(position.Line) && token.Character >= int(position.Character)) {
+			break
+		}
+		last = i
+	}
+	if last >= 0 && tokens[last].TokenType == lox.IDENTIFIER {
+		last-- // member name being typed
+	}
+	if last < 1 || tokens[last].TokenType != lox.DOT {
+		return nil, false
+	}
+
+	declarations := lox.Declarations(loxService.AST)
+	classes := make([]*lox.Declaration, 0)
+	methodsOnly := false
+	switch object := tokens[last-1]; object.TokenType {
+	case lox.THIS:
+		classes = append(classes, enclosingClass(declarations, position))
+	case lox.SUPER:
+		methodsOnly = true // super only looks up methods
+		if class := enclosingClass(declarations, position); class != nil {
+			if parent := class.Node.(*lox.ClassDecl).Parent; parent != nil {
+				parentName, _ := parent.Value.(string)
+				classes = append(classes, lox.FindClass(declarations, parentName))
+			}
+		}
+	default:
+		if class := loxService.instanceClass(declarations, object); class != nil {
+			classes = append(classes, class)
+			break
+		}
+		// the type of an arbitrary object is unknown, offer the members of every class
+		for _, declaration := range declarations {
+			if declaration.Kind == lox.DECLARATION_CLASS {
+				classes = append(classes, declaration)
+			}
+		}
+	}
+
+	items := make([]lsp.CompletionItem, 0)
+	seen := make(map[string]bool)
+	for _, class := range classes {
+		if class == nil {
+			continue
+		}
+		for _, member := range lox.Members(declarations, class) {
+			name, _ := member.Name.Value.(string)
+			if seen[name] || (methodsOnly && member.Kind != lox.MEMBER_METHOD) {
+				continue
+			}
+			seen[name] = true
+			kind := lsp.CompletionItemKindField
+			if member.Kind == lox.MEMBER_METHOD {
+				kind = lsp.CompletionItemKindMethod
+			}
+			memberClass, _ := member.Class.Name.Value.(string)
+			items = append(items, lsp.CompletionItem{Label: name, Kind: kind, Detail: memberClass + "." + member.Signature()})
+		}
+	}
+	return items, true
+}
+
+// instanceClass finds the class of a variable initialized with a constructor call
+func (loxService *DocumentService) instanceClass(declarations []*lox.Declaration, object lox.Token) *lox.Declaration {
+	if object.TokenType != lox.IDENTIFIER {
+		return nil
+	}
+	definition, _, found := loxService.getSymbol(lsp.Position{Line: uint(object.Line), Character: uint(object.Character)})
+	if !found {
+		return nil
+	}
+	for _, declaration := range declarations {
+		if declaration.Name != definition {
+			continue
+		}
+		varDecl, ok := declaration.Node.(*lox.VarDecl)
+		if !ok {
+			return nil
+		}
+		call, ok := varDecl.Value.(*lox.Call)
+		if !ok {
+			return nil
+		}
+		callee, ok := call.Callee.(*lox.Variable)
+		if !ok {
+			return nil
+		}
+		name, _ := callee.Identifier.Value.(string)
+		return lox.FindClass(declarations, name)
+	}
+	return nil
+}
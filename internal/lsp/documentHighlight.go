@@ -0,0 +1,50 @@
+package lsp
+
+import (
+	"context"
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+)
+
+/* occurrences of the symbol under the cursor, declarations and assignments count as writes */
+
+func (loxService *DocumentService) GetDocumentHighlights(ctx context.Context, position lsp.Position) []lsp.DocumentHighlight {
+	references := loxService.GetReferences(ctx, position, true)
+	if references == nil {
+		return nil
+	}
+
+	writes := make(map[lsp.Position]bool)
+	for definition := range loxService.SymbolMap {
+		writes[tokenStart(definition)] = true
+	}
+	for _, node := range loxService.References {
+		if variable, ok := node.(*lox.Variable); ok && variable.Write {
+			writes[tokenStart(variable.Identifier)] = true
+		}
+	}
+
+	tokens := make(map[lsp.Position]lox.Token)
+	for _, token := range loxService.Tokens {
+		if token.TokenType == lox.IDENTIFIER {
+			tokens[tokenStart(token)] = token
+		}
+	}
+
+	highlights := make([]lsp.DocumentHighlight, 0, len(references))
+	for _, reference := range references {
+		start := loxService.lines.utf16Position(reference)
+		highlight := lsp.DocumentHighlight{
+			Range: lsp.Range{Start: start, End: start},
+			Kind:  lsp.DocumentHighlightKindRead,
+		}
+		if token, ok := tokens[reference]; ok {
+			highlight.Range = loxService.lines.tokenRange(token)
+		}
+		if writes[reference] {
+			highlight.Kind = lsp.DocumentHighlightKindWrite
+		}
+		highlights = append(highlights, highlight)
+	}
+	return highlights
+}
@@ -0,0 +1,74 @@
+package lsp
+
+import (
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+)
+
+/* document outline built from the declarations in the AST */
+
+func (loxService *DocumentService) GetDocumentSymbols() []lsp.DocumentSymbol {
+	children := make(map[*lox.Declaration][]*lox.Declaration)
+	for _, declaration := range lox.Declarations(loxService.AST) {
+		children[declaration.Parent] = append(children[declaration.Parent], declaration)
+	}
+	return documentSymbols(loxService.lines, children, nil)
+}
+
+func documentSymbols(lines *lineIndex, children map[*lox.Declaration][]*lox.Declaration, parent *lox.Declaration) []lsp.DocumentSymbol {
+	symbols := make([]lsp.DocumentSymbol, 0, len(children[parent]))
+	for _, declaration := range children[parent] {
+		name, ok := declaration.Name.Value.(string)
+		if !ok {
+			continue
+		}
+		symbols = append(symbols, lsp.DocumentSymbol{
+			Name:           name,
+			Detail:         declaration.Signature(),
+			Kind:           symbolKind(declaration),
+			Range:          declarationRange(lines, declaration),
+			SelectionRange: lines.tokenRange(declaration.Name),
+			Children:       documentSymbols(lines, children, declaration),
+		})
+	}
+	return symbols
+}
+
+func symbolKind(declaration *lox.Declaration) int {
+	switch declaration.Kind {
+	case lox.DECLARATION_CLASS:
+		return lsp.SymbolKindClass
+	case lox.DECLARATION_FUNCTION:
+		return lsp.SymbolKindFunction
+	case lox.DECLARATION_METHOD:
+		if name, _ := declaration.Name.Value.(string); name == "init" {
+			return lsp.SymbolKindConstructor
+		}
+		return lsp.SymbolKindMethod
+	}
+	return lsp.SymbolKindVariable
+}
+
+// declarationRange spans the whole declaration, from its keyword to its closing token
+func declarationRange(lines *lineIndex, declaration *lox.Declaration) lsp.Range {
+	switch declaration.Node.(type) {
+	case *lox.VarDecl, *lox.FuncDecl, *lox.ClassDecl:
+	default:
+		return lines.tokenRange(declaration.Name)
+	}
+
+	declarationRange := lines.spanRange(declaration.Node.GetSpan())
+	// incomplete declarations may end before their name, the range has to contain it
+	nameRange := lines.tokenRange(declaration.Name)
+	if positionBefore(nameRange.Start, declarationRange.Start) {
+		declarationRange.Start = nameRange.Start
+	}
+	if positionBefore(declarationRange.End, nameRange.End) {
+		declarationRange.End = nameRange.End
+	}
+	return declarationRange
+}
+
+func positionBefore(position lsp.Position, other lsp.Position) bool {
+	return position.Line < other.Line || (position.Line == other.Line && position.Character < other.Character)
+}
@@ -0,0 +1,183 @@
+package lsp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+/* open documents, each analysed by its own worker so parses apply in the order the edits arrived */
+
+// defaultAnalysisDelay is how long a document has to stay unchanged before it is analysed again
+const defaultAnalysisDelay = 200 * time.Millisecond
+
+type DocumentStore struct {
+	mutex     sync.RWMutex
+	documents map[string]*DocumentService
+}
+
+type parseJob struct {
+	ctx     context.Context
+	code    string
+	version int
+	delay   time.Duration
+}
+
+func (store *DocumentStore) Initialize() {
+	defer store.mutex.Unlock()
+	store.mutex.Lock()
+	store.documents = make(map[string]*DocumentService)
+}
+
+// Open starts analysing a document, a document already open under the uri is replaced
+func (store *DocumentStore) Open(uri string, text string, version int) {
+	document := &DocumentService{Uri: uri, Text: text}
+	document.Initialize()
+	document.start()
+
+	store.mutex.Lock()
+	previous, replaced := store.documents[uri]
+	store.documents[uri] = document
+	store.mutex.Unlock()
+	if replaced {
+		previous.stop()
+	}
+
+	// a freshly opened document is analysed right away
+	document.enqueue(text, version, 0)
+}
+
+func (store *DocumentStore) Get(uri string) (*DocumentService, bool) {
+	defer store.mutex.RUnlock()
+	store.mutex.RLock()
+	document, ok := store.documents[uri]
+	return document, ok
+}
+
+// Snapshot copies the latest analysis of a document, a request reading it sees tokens, AST and symbols of one version
+func (store *DocumentStore) Snapshot(uri string) (*DocumentService, bool) {
+	document, ok := store.Get(uri)
+	if !ok {
+		return nil, false
+	}
+	return document.snapshot(), true
+}
+
+// Close returns once nothing more is published for the document
+func (store *DocumentStore) Close(uri string) {
+	store.mutex.Lock()
+	document, ok := store.documents[uri]
+	delete(store.documents, uri)
+	store.mutex.Unlock()
+	if ok {
+		document.stop()
+	}
+}
+
+func (loxService *DocumentService) start() {
+	loxService.pending = make(chan struct{}, 1)
+	loxService.done = make(chan struct{})
+	go loxService.work()
+}
+
+// stop ends the worker, a parse already running is discarded and one being published finishes first
+func (loxService *DocumentService) stop() {
+	defer loxService.publishing.Unlock()
+	loxService.publishing.Lock()
+	defer loxService.Mutex.Unlock()
+	loxService.Mutex.Lock()
+	if !loxService.closed {
+		loxService.closed = true
+		close(loxService.done)
+		if loxService.cancel != nil {
+			loxService.cancel()
+		}
+	}
+}
+
+// enqueue never blocks, the request loop hands over the text and moves on,
+// the job it replaces is cancelled whether it is still waiting or already parsing
+func (loxService *DocumentService) enqueue(code string, version int, delay time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	loxService.Mutex.Lock()
+	if loxService.cancel != nil {
+		loxService.cancel()
+	}
+	loxService.next = &parseJob{ctx: ctx, code: code, version: version, delay: delay}
+	loxService.cancel = cancel
+	loxService.Mutex.Unlock()
+
+	select {
+	case loxService.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (loxService *DocumentService) work() {
+	for {
+		select {
+		case <-loxService.done:
+			return
+		case <-loxService.pending:
+		}
+		if !loxService.settle() {
+			return
+		}
+
+		loxService.Mutex.Lock()
+		job := loxService.next
+		loxService.next = nil
+		loxService.Mutex.Unlock()
+
+		if job != nil {
+			loxService.ParseCode(job.ctx, job.code, job.version)
+		}
+	}
+}
+
+// settle waits until no change arrived for the delay of the latest job, false once the document is closed
+func (loxService *DocumentService) settle() bool {
+	for {
+		loxService.Mutex.Lock()
+		var delay time.Duration
+		if loxService.next != nil {
+			delay = loxService.next.delay
+		}
+		loxService.Mutex.Unlock()
+		if delay <= 0 {
+			return true
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-loxService.done:
+			timer.Stop()
+			return false
+		case <-loxService.pending:
+			// a newer change restarts the wait
+			timer.Stop()
+		case <-timer.C:
+			return true
+		}
+	}
+}
+
+func (loxService *DocumentService) snapshot() *DocumentService {
+	defer loxService.Mutex.Unlock()
+	loxService.Mutex.Lock()
+	return &DocumentService{
+		AST:        loxService.AST,
+		Tokens:     loxService.Tokens,
+		References: loxService.References,
+		SymbolMap:  loxService.SymbolMap,
+		ScopeTable: loxService.ScopeTable,
+		Errors:     loxService.Errors,
+		Uri:        loxService.Uri,
+		Text:       loxService.Text,
+		Source:     loxService.Source,
+		lines:      loxService.lines,
+		Version:    loxService.Version,
+		EOF:        loxService.EOF,
+		IsError:    loxService.IsError,
+	}
+}
@@ -0,0 +1,106 @@
+package lsp
+
+import (
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+	"slices"
+	"strings"
+)
+
+/* folding of scopes, comment runs and multi-line strings */
+
+// GetFoldingRanges folds between braces, with lineFoldingOnly the closing brace stays visible
+func (loxService *DocumentService) GetFoldingRanges(lineFoldingOnly bool) []lsp.FoldingRange {
+	ranges := make([]lsp.FoldingRange, 0)
+	add := func(start lsp.Position, end lsp.Position, kind string, lastLineShown bool) {
+		if lineFoldingOnly {
+			if lastLineShown {
+				end.Line--
+			}
+			if end.Line > start.Line {
+				ranges = append(ranges, lsp.FoldingRange{StartLine: start.Line, EndLine: end.Line, Kind: kind})
+			}
+			return
+		}
+		start, end = loxService.lines.utf16Position(start), loxService.lines.utf16Position(end)
+		if end.Line > start.Line {
+			ranges = append(ranges, lsp.FoldingRange{
+				StartLine:      start.Line,
+				StartCharacter: &start.Character,
+				EndLine:        end.Line,
+				EndCharacter:   &end.Character,
+				Kind:           kind,
+			})
+		}
+	}
+
+	for scope := range loxService.ScopeTable {
+		if scope.ScopeContext == lox.GLOBAL_CONTEXT {
+			continue
+		}
+		start := lsp.Position{Line: uint(scope.StartLine), Character: uint(scope.StartChar)}
+		if scope.ScopeContext == lox.FUNCTION_CONTEXT || scope.ScopeContext == lox.METHOD_CONTEXT {
+			// function scopes open at the parameter list, fold the body only
+			brace, found := loxService.braceAfter(start)
+			if !found {
+				continue
+			}
+			start = brace
+		}
+		start.Character++
+		add(start, lsp.Position{Line: uint(scope.EndLine), Character: uint(scope.EndChar)}, "", true)
+	}
+
+	var run []lox.Token
+	flushComments := func() {
+		if len(run) > 1 {
+			first, last := run[0], run[len(run)-1]
+			add(lsp.Position{Line: uint(first.Line), Character: uint(first.Character)},
+				lsp.Position{Line: uint(last.Line), Character: uint(last.Character + last.Length)}, "comment", false)
+		}
+		run = nil
+	}
+	for i, token := range loxService.Tokens {
+		switch token.TokenType {
+		case lox.COMMENT:
+			ownLine := i == 0 || loxService.Tokens[i-1].TokenType == lox.NEWLINE
+			if !ownLine {
+				flushComments()
+				continue
+			}
+			if len(run) > 0 && run[len(run)-1].Line+1 != token.Line {
+				flushComments()
+			}
+			run = append(run, token)
+		case lox.NEWLINE:
+		case lox.STRING:
+			flushComments()
+			value, _ := token.Value.(string)
+			if lines := strings.Split(value, "\n"); len(lines) > 1 {
+				add(lsp.Position{Line: uint(token.Line), Character: uint(token.Character + 1)},
+					lsp.Position{Line: uint(token.Line + len(lines) - 1), Character: uint(len(lines[len(lines)-1]))}, "", false)
+			}
+		default:
+			flushComments()
+		}
+	}
+	flushComments()
+
+	slices.SortFunc(ranges, func(a lsp.FoldingRange, b lsp.FoldingRange) int {
+		if a.StartLine != b.StartLine {
+			return int(a.StartLine) - int(b.StartLine)
+		}
+		return int(b.EndLine) - int(a.EndLine)
+	})
+	return ranges
+}
+
+func (loxService *DocumentService) braceAfter(position lsp.Position) (lsp.Position, bool) {
+	for _, token := range loxService.Tokens {
+		tokenStart := lsp.Position{Line: uint(token.Line), Character: uint(token.Character)}
+		if token.TokenType == lox.BRACELEFT && !positionBefore(tokenStart, position) {
+			return tokenStart, true
+		}
+	}
+	return lsp.Position{}, false
+}
@@ -2,6 +2,7 @@ package lsp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	lsp "lox-server/internal/lsp/types"
@@ -34,14 +35,48 @@ func handleRequest(msg string) ([]byte, error) {
 	var requestObj lsp.JsonRpcRequest
 
 	if err := json.Unmarshal([]byte(msg), &requestObj); err != nil {
-		return nil, fmt.Errorf("invalid JSON: %v", err)
+		// the id can not be read from a message that does not parse
+		return json.Marshal(lsp.JsonRpcResponse{
+			JsonRpc: "2.0",
+			Id:      nil,
+			Error:   &lsp.ResponseError{Code: lsp.ParseError, Message: fmt.Sprintf("Parse error: %v", err)},
+		})
 	}
 
 	if requestObj.Method == "" {
+		// answers to requests sent by the server carry an id and no method
+		if requestObj.Id != nil {
+			var responseObj lsp.ClientResponse
+			if err := json.Unmarshal([]byte(msg), &responseObj); err == nil {
+				serverState.clientRequests.resolve(responseObj)
+			}
+		}
+		return nil, nil
+	}
+
+	rejected := initializeCheck(requestObj)
+	if rejected == nil {
+		rejected = shutdownCheck(requestObj)
+	}
+	if rejected != nil {
+		// notifications can not be answered and are dropped
+		if requestObj.Id == nil {
+			return nil, nil
+		}
+		return json.Marshal(*rejected)
+	}
+
+	if runsConcurrently(requestObj) {
+		serverState.requests.submit(requestObj)
 		return nil, nil
 	}
+	return respond(context.Background(), requestObj)
+}
 
-	responseObj, err := processRequest(requestObj)
+// respond processes a request and encodes its response, nil for notifications,
+// handlers walking the workspace stop early once ctx is cancelled
+func respond(ctx context.Context, requestObj lsp.JsonRpcRequest) ([]byte, error) {
+	responseObj, err := processRequest(ctx, requestObj)
 	if err != nil {
 		return nil, fmt.Errorf("invalid Request: %v", err)
 	}
@@ -57,20 +92,9 @@ func handleRequest(msg string) ([]byte, error) {
 	return response, nil
 }
 
-func processRequest(request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error) {
-	switch request.Id.(type) {
-	case string:
-		id, err := strconv.Atoi(request.Id.(string))
-		if err == nil {
-			serverState.idCount = id
-		}
-	case int:
-		serverState.idCount = request.Id.(int)
-	}
-
+func processRequest(ctx context.Context, request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error) {
 	switch request.Method {
 	case "initialize":
-		serverState.initialized = true
 		return protocolInitialize(request)
 	case "shutdown":
 		serverState.shutdown = true
@@ -82,6 +106,9 @@ func processRequest(request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error) {
 			syscall.Exit(1)
 		}
 	case "initialized":
+		if serverState.capabilities.Workspace.DidChangeWatchedFiles.DynamicRegistration {
+			sendRequest("client/registerCapability", registerFileWatcher(), nil)
+		}
 		return nil, nil
 	case "textDocument/didOpen":
 		var params lsp.DidOpenTextDocumentParams
@@ -90,11 +117,7 @@ func processRequest(request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error) {
 			return nil, nil
 		}
 
-		serverState.documents[params.TextDocument.Uri] = &DocumentService{Uri: params.TextDocument.Uri}
-		serverState.documents[params.TextDocument.Uri].Initialize()
-		go (func() {
-			serverState.documents[params.TextDocument.Uri].ParseCode(params.TextDocument.Text, params.TextDocument.Version)
-		})()
+		serverState.documents.Open(params.TextDocument.Uri, params.TextDocument.Text, params.TextDocument.Version)
 		return nil, nil
 	case "textDocument/didClose":
 		var params lsp.DidCloseTextDocumentParams
@@ -103,7 +126,8 @@ func processRequest(request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error) {
 			return nil, nil
 		}
 
-		delete(serverState.documents, params.TextDocument.Uri)
+		serverState.documents.Close(params.TextDocument.Uri)
+		serverState.workspace.Close(params.TextDocument.Uri)
 		return nil, nil
 	case "textDocument/didChange":
 		var params lsp.DidChangeTextDocumentParams
@@ -112,26 +136,110 @@ func processRequest(request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error) {
 			return nil, nil
 		}
 
+		// changes to a document that was never opened have no buffer to apply to
+		document, ok := serverState.documents.Get(params.TextDocument.Uri)
+		if !ok {
+			return nil, nil
+		}
+
+		// edits are applied in the order they arrive, the document's worker parses them in that order
+		code := document.ApplyChanges(params.ContentChanges)
+		document.enqueue(code, params.TextDocument.Version, serverState.analysisDelay)
+		return nil, nil
+	case "workspace/didChangeWatchedFiles":
+		var params lsp.DidChangeWatchedFilesParams
+		err := getRequestValues(&params, request)
+		if err != nil {
+			return nil, nil
+		}
+
+		go (func() {
+			for _, change := range params.Changes {
+				if change.Type == 3 {
+					serverState.workspace.Remove(change.Uri)
+				} else {
+					serverState.workspace.IndexFile(change.Uri)
+				}
+			}
+		})()
+		return nil, nil
+	case "workspace/didChangeWorkspaceFolders":
+		var params lsp.DidChangeWorkspaceFoldersParams
+		err := getRequestValues(&params, request)
+		if err != nil {
+			return nil, nil
+		}
+
 		go (func() {
-			serverState.documents[params.TextDocument.Uri].ParseCode(params.ContentChanges[0].Text, params.TextDocument.Version)
+			for _, folder := range params.Event.Removed {
+				serverState.workspace.RemoveFolder(folder.Uri)
+			}
+			for _, folder := range params.Event.Added {
+				serverState.workspace.AddFolder(folder.Uri)
+			}
 		})()
 		return nil, nil
+	case "workspace/symbol":
+		return protocolWorkspaceSymbol(ctx, request), nil
 	case "textDocument/definition":
 		return protocolDefinition(request), nil
 	case "textDocument/references":
-		return protocolReferences(request), nil
+		return protocolReferences(ctx, request), nil
 	case "textDocument/formatting":
 		return protocolFormatting(request), nil
 	case "textDocument/completion":
 		return protocolCompletion(request), nil
+	case "textDocument/hover":
+		return protocolHover(request), nil
+	case "textDocument/prepareRename":
+		return protocolPrepareRename(request), nil
+	case "textDocument/rename":
+		return protocolRename(request), nil
+	case "textDocument/documentSymbol":
+		return protocolDocumentSymbol(request), nil
+	case "textDocument/codeAction":
+		return protocolCodeAction(request), nil
+	case "textDocument/signatureHelp":
+		return protocolSignatureHelp(request), nil
+	case "textDocument/prepareTypeHierarchy":
+		return protocolPrepareTypeHierarchy(request), nil
+	case "typeHierarchy/supertypes":
+		return protocolTypeHierarchySupertypes(ctx, request), nil
+	case "typeHierarchy/subtypes":
+		return protocolTypeHierarchySubtypes(ctx, request), nil
+	case "textDocument/implementation":
+		return protocolImplementation(ctx, request), nil
+	case "textDocument/prepareCallHierarchy":
+		return protocolPrepareCallHierarchy(request), nil
+	case "callHierarchy/incomingCalls":
+		return protocolIncomingCalls(request), nil
+	case "callHierarchy/outgoingCalls":
+		return protocolOutgoingCalls(request), nil
+	case "textDocument/documentHighlight":
+		return protocolDocumentHighlight(ctx, request), nil
+	case "textDocument/foldingRange":
+		return protocolFoldingRange(request), nil
+	case "textDocument/selectionRange":
+		return protocolSelectionRange(request), nil
 	case "textDocument/semanticTokens/full":
 		return protocolSemanticTokens(request), nil
-	case "/cancelRequest":
+	case "$/cancelRequest":
+		var params lsp.CancelParams
+		err := getRequestValues(&params, request)
+		if err != nil {
+			return nil, nil
+		}
+
+		serverState.requests.cancel(params.Id)
 		return nil, nil
 
 	}
 
-	return nil, fmt.Errorf("Invalid Method: %v", request.Method)
+	// unknown notifications are ignored, $/ ones included
+	if request.Id == nil {
+		return nil, nil
+	}
+	return errorResponse(request, lsp.MethodNotFound, fmt.Sprintf("Method not found: %s", request.Method)), nil
 }
 
 func getRequestValues[T any](document *T, request lsp.JsonRpcRequest) error {
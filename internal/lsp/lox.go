@@ -1,12 +1,15 @@
 package lsp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"lox-server/internal/lox"
 	lsp "lox-server/internal/lsp/types"
+	"slices"
 	"strings"
 	"sync"
+	"unicode/utf16"
 )
 
 /* document level logic like language features and state are handled here*/
@@ -19,9 +22,20 @@ type DocumentService struct {
 	ScopeTable map[lox.ScopeRange][]lox.Token
 	Errors     []lox.CompileError
 	Uri        string
+	Text       string // authoritative buffer, kept in sync through didChange
+	Source     string // text the tokens and AST were parsed from, it lags behind Text while a parse is queued
+	Version    int
 	Mutex      sync.Mutex
 	EOF        lox.Token
 	IsError    bool
+
+	lines      *lineIndex // columns of Source, converts the positions sent to the client
+	publishing sync.Mutex // orders publishing a parse against stopping the document
+	next       *parseJob
+	cancel     context.CancelFunc
+	pending    chan struct{}
+	done       chan struct{}
+	closed     bool
 }
 
 func (loxService *DocumentService) Initialize() {
@@ -31,15 +45,32 @@ func (loxService *DocumentService) Initialize() {
 	loxService.Errors = make([]lox.CompileError, 0)
 }
 
-func (loxService *DocumentService) ParseCode(code string, version int) {
+// ParseCode analyses code and publishes its diagnostics unless ctx was cancelled by a newer version
+func (loxService *DocumentService) ParseCode(ctx context.Context, code string, version int) {
+	if ctx.Err() != nil {
+		return
+	}
 	tokens, ast, compileErrors, references, symbolMap, scopeTable, err := lox.ParseCode(code)
 	if err != nil {
 		return
 	}
+	lines := newLineIndex(code)
+
+	// held until the diagnostics are out, so stop cannot slip between storing and publishing
+	defer loxService.publishing.Unlock()
+	loxService.publishing.Lock()
 
-	defer loxService.Mutex.Unlock()
 	loxService.Mutex.Lock()
+	// a closed document or one already analysed at a newer version keeps its results,
+	// cancellation is checked under the lock so a superseded version is never published
+	if loxService.closed || version < loxService.Version || ctx.Err() != nil {
+		loxService.Mutex.Unlock()
+		return
+	}
 
+	loxService.Source = code
+	loxService.lines = lines
+	loxService.Version = version
 	loxService.AST = ast
 	loxService.Tokens = tokens
 	loxService.References = references
@@ -48,17 +79,63 @@ func (loxService *DocumentService) ParseCode(code string, version int) {
 	loxService.ScopeTable = scopeTable
 	loxService.EOF = tokens[len(tokens)-1]
 	loxService.IsError = false
-
 	for _, error := range compileErrors {
 		loxService.IsError = error.Source < lox.ERROR_RESOLVER || loxService.IsError
 	}
+	loxService.Mutex.Unlock()
 
-	responseObj := diagnosticNotification(compileErrors, loxService.Uri, version)
+	// readers only wait for the results above, not for the index or the client
+	serverState.workspace.UpdateOpen(loxService.Uri, ast, lines)
+	responseObj := diagnosticNotification(compileErrors, lines, loxService.Uri, version)
 	response, err := json.Marshal(responseObj)
 	sendNotification(response)
 }
 
+// ApplyChanges applies the content changes of a didChange notification in order and returns the new text
+func (loxService *DocumentService) ApplyChanges(changes []lsp.TextDocumentContentChangeEvent) string {
+	defer loxService.Mutex.Unlock()
+	loxService.Mutex.Lock()
+
+	for _, change := range changes {
+		if change.TextRange == nil {
+			loxService.Text = change.Text
+			continue
+		}
+		start := offsetAt(loxService.Text, change.TextRange.Start)
+		end := offsetAt(loxService.Text, change.TextRange.End)
+		if end < start {
+			start, end = end, start
+		}
+		loxService.Text = loxService.Text[:start] + change.Text + loxService.Text[end:]
+	}
+	return loxService.Text
+}
+
+// offsetAt converts a position, whose character counts UTF-16 code units, to a byte offset in text
+func offsetAt(text string, position lsp.Position) int {
+	offset := 0
+	for line := 0; line < int(position.Line); line++ {
+		next := strings.IndexByte(text[offset:], '\n')
+		if next < 0 {
+			return len(text)
+		}
+		offset += next + 1
+	}
+
+	units := 0
+	for i, char := range text[offset:] {
+		if units >= int(position.Character) || char == '\n' {
+			return offset + i
+		}
+		units += utf16.RuneLen(char)
+	}
+	return len(text)
+}
+
 func (loxService *DocumentService) GetCompletion(position lsp.Position) []lsp.CompletionItem {
+	if members, ok := loxService.getMemberCompletion(position); ok {
+		return members
+	}
 	items := make([]lsp.CompletionItem, 0)
 	var scope *lox.ScopeRange = nil
 
@@ -111,9 +188,21 @@ func (loxService *DocumentService) GetToken(position lsp.Position) lox.Token {
 	return currToken
 }
 
-func (loxService *DocumentService) GetDefinition(position lsp.Position) (lsp.Position, bool) {
+// GetDefinition lists the declarations the symbol at the cursor refers to,
+// a property of an object whose class is unknown can refer to several methods
+func (loxService *DocumentService) GetDefinition(position lsp.Position) ([]lsp.Position, bool) {
 	for _, definable := range loxService.References {
 		switch definable.(type) {
+		case *lox.GetExpr:
+			get := definable.(*lox.GetExpr)
+			if tokenAtCursor(get.Property, position) {
+				return tokenPositions(get.Definitions), true
+			}
+		case *lox.Super:
+			super := definable.(*lox.Super)
+			if tokenAtCursor(super.Property, position) {
+				return tokenPositions(super.Definitions), true
+			}
 		case *lox.Variable:
 			variable := definable.(*lox.Variable)
 			name, ok := variable.Identifier.Value.(string)
@@ -125,17 +214,242 @@ func (loxService *DocumentService) GetDefinition(position lsp.Position) (lsp.Pos
 				variable.Identifier.Character+len(name) >= int(position.Character)
 
 			if atCursor {
-				return lsp.Position{
-					Line:      uint(variable.Definition.Line),
-					Character: uint(variable.Definition.Character),
-				}, true
+				return tokenPositions([]lox.Token{variable.Definition}), true
 			}
 		default:
 			continue
 		}
 	}
-	return position, false
+	// fields are declared by their first assignment on this
+	if token := loxService.GetToken(position); token.TokenType == lox.IDENTIFIER && tokenAtCursor(token, position) {
+		if field, found := loxService.fieldDefinition(token, position); found {
+			return tokenPositions([]lox.Token{field}), true
+		}
+	}
+	return []lsp.Position{position}, false
+
+}
+
+func (loxService *DocumentService) fieldDefinition(property lox.Token, position lsp.Position) (lox.Token, bool) {
+	index := slices.IndexFunc(loxService.Tokens, func(token lox.Token) bool { return token == property })
+	if index < 2 || loxService.Tokens[index-1].TokenType != lox.DOT || loxService.Tokens[index-2].TokenType != lox.THIS {
+		return lox.Token{}, false
+	}
+	declarations := lox.Declarations(loxService.AST)
+	class := enclosingClass(declarations, position)
+	if class == nil {
+		return lox.Token{}, false
+	}
+	for _, member := range lox.Members(declarations, class) {
+		if member.Kind == lox.MEMBER_FIELD && member.Name.Value == property.Value {
+			return member.Name, true
+		}
+	}
+	return lox.Token{}, false
+}
+
+func tokenPositions(tokens []lox.Token) []lsp.Position {
+	positions := make([]lsp.Position, 0, len(tokens))
+	for _, token := range tokens {
+		positions = append(positions, tokenStart(token))
+	}
+	return positions
+}
+
+// tokenStart is in byte columns, for comparing with positions the client sent once they are converted
+func tokenStart(token lox.Token) lsp.Position {
+	return lsp.Position{Line: uint(token.Line), Character: uint(token.Character)}
+}
+
+func (loxService *DocumentService) GetHover(position lsp.Position) (string, lox.Token, bool) {
+	token := loxService.GetToken(position)
+	name, ok := token.Value.(string)
+	if token.TokenType == lox.IDENTIFIER && ok && slices.Contains(lox.NativeFunctions, name) && tokenAtCursor(token, position) {
+		return fmt.Sprintf("```lox\nfun %s()\n```\nnative function", name), token, true
+	}
+
+	definition, reference, found := loxService.getSymbol(position)
+	if !found {
+		return "", token, false
+	}
+
+	var declaration *lox.Declaration
+	for _, candidate := range lox.Declarations(loxService.AST) {
+		if candidate.Name == definition {
+			declaration = candidate
+			break
+		}
+	}
+	if declaration == nil {
+		return "", token, false
+	}
+
+	var hover strings.Builder
+	hover.WriteString("```lox\n")
+	switch declaration.Kind {
+	case lox.DECLARATION_METHOD:
+		className, _ := declaration.Parent.Name.Value.(string)
+		hover.WriteString(fmt.Sprintf("method %s.%s", className, declaration.Signature()))
+	case lox.DECLARATION_PARAMETER:
+		hover.WriteString(fmt.Sprintf("param %s", declaration.Signature()))
+	default:
+		hover.WriteString(declaration.Signature())
+	}
+	hover.WriteString("\n```\n")
+
+	kinds := map[int]string{
+		lox.DECLARATION_VARIABLE:  "variable",
+		lox.DECLARATION_FUNCTION:  "function",
+		lox.DECLARATION_METHOD:    "method",
+		lox.DECLARATION_CLASS:     "class",
+		lox.DECLARATION_PARAMETER: "parameter",
+	}
+	hover.WriteString(fmt.Sprintf("%s declared on line %d", kinds[declaration.Kind], declaration.Name.Line+1))
+	if declaration.Kind == lox.DECLARATION_PARAMETER {
+		hover.WriteString(fmt.Sprintf(" in `%s`", declaration.Parent.Signature()))
+	}
+
+	if documentation := declaration.Documentation(); documentation != "" {
+		hover.WriteString("\n\n---\n")
+		hover.WriteString(documentation)
+	}
+
+	return hover.String(), reference, true
+}
+
+func (loxService *DocumentService) PrepareRename(position lsp.Position) (lox.Token, error) {
+	token := loxService.GetToken(position)
+	if !tokenAtCursor(token, position) {
+		return token, fmt.Errorf("No symbol found at cursor")
+	}
+
+	switch token.TokenType {
+	case lox.THIS:
+		return token, fmt.Errorf("'this' cannot be renamed")
+	case lox.SUPER:
+		return token, fmt.Errorf("'super' cannot be renamed")
+	case lox.IDENTIFIER:
+	default:
+		return token, fmt.Errorf("Only identifiers can be renamed")
+	}
+
+	name, ok := token.Value.(string)
+	if !ok {
+		return token, fmt.Errorf("No symbol found at cursor")
+	}
+	if slices.Contains(lox.NativeFunctions, name) {
+		return token, fmt.Errorf("Native function %s cannot be renamed", name)
+	}
+	if _, _, found := loxService.getSymbol(position); !found {
+		return token, fmt.Errorf("No definition found for %s", name)
+	}
+	return token, nil
+}
+
+func (loxService *DocumentService) Rename(position lsp.Position, newName string) ([]lsp.TextEdit, error) {
+	if _, err := loxService.PrepareRename(position); err != nil {
+		return nil, err
+	}
+	definition, _, _ := loxService.getSymbol(position)
+	if err := loxService.renameCollision(definition, newName); err != nil {
+		return nil, err
+	}
+
+	edits := make([]lsp.TextEdit, 0, len(loxService.SymbolMap[definition])+1)
+	for _, token := range append([]lox.Token{definition}, loxService.SymbolMap[definition]...) {
+		edits = append(edits, lsp.TextEdit{
+			Range:   loxService.lines.tokenRange(token),
+			NewText: newName,
+		})
+	}
+	return edits, nil
+}
+
+// getSymbol returns the definition of the symbol under the cursor and the token the cursor is on
+func (loxService *DocumentService) getSymbol(position lsp.Position) (lox.Token, lox.Token, bool) {
+	for definition := range loxService.SymbolMap {
+		if tokenAtCursor(definition, position) {
+			return definition, definition, true
+		}
+	}
+	for _, definable := range loxService.References {
+		variable, ok := definable.(*lox.Variable)
+		if !ok {
+			continue
+		}
+		if tokenAtCursor(variable.Identifier, position) {
+			return variable.Definition, variable.Identifier, true
+		}
+	}
+	return lox.Token{}, lox.Token{}, false
+}
 
+// renameCollision reports a binding named newName that would capture a reference of the definition,
+// or whose references would be captured by the renamed definition
+func (loxService *DocumentService) renameCollision(definition lox.Token, newName string) error {
+	declaring, found := loxService.declaringScope(definition)
+	if !found {
+		return nil
+	}
+	occurrences := append([]lox.Token{definition}, loxService.SymbolMap[definition]...)
+
+	for scope, definitions := range loxService.ScopeTable {
+		// methods are reached through instances, their names do not bind in the class body
+		if scope.ScopeContext == lox.CLASS_CONTEXT && declaring.ScopeContext != lox.CLASS_CONTEXT {
+			continue
+		}
+		for _, existing := range definitions {
+			if name, ok := existing.Value.(string); !ok || name != newName || existing == definition {
+				continue
+			}
+			if scope == declaring {
+				return fmt.Errorf("%s is already declared in this scope at line %d", newName, existing.Line+1)
+			}
+			// a binding between the declaration and one of its references would capture that reference
+			if scopeContains(declaring, scopeStart(scope)) && slices.ContainsFunc(occurrences, func(occurrence lox.Token) bool {
+				return scopeContains(scope, tokenStart(occurrence))
+			}) {
+				return fmt.Errorf("%s declared at line %d would capture a reference to the renamed symbol", newName, existing.Line+1)
+			}
+			// a binding further out whose references fall into the declaring scope would be shadowed
+			if scopeContains(scope, scopeStart(declaring)) {
+				for _, reference := range loxService.SymbolMap[existing] {
+					if scopeContains(declaring, tokenStart(reference)) {
+						return fmt.Errorf("%s used at line %d would refer to the renamed symbol", newName, reference.Line+1)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (loxService *DocumentService) declaringScope(definition lox.Token) (lox.ScopeRange, bool) {
+	for scope, definitions := range loxService.ScopeTable {
+		if slices.Contains(definitions, definition) {
+			return scope, true
+		}
+	}
+	return lox.ScopeRange{}, false
+}
+
+func scopeStart(scope lox.ScopeRange) lsp.Position {
+	return lsp.Position{Line: uint(scope.StartLine), Character: uint(scope.StartChar)}
+}
+
+// scopeContains treats the global scope as covering the whole document
+func scopeContains(scope lox.ScopeRange, position lsp.Position) bool {
+	if scope.ScopeContext == lox.GLOBAL_CONTEXT {
+		return true
+	}
+	end := lsp.Position{Line: uint(scope.EndLine), Character: uint(scope.EndChar)}
+	return !positionBefore(position, scopeStart(scope)) && !positionBefore(end, position)
+}
+
+func tokenAtCursor(token lox.Token, position lsp.Position) bool {
+	return token.Line == int(position.Line) &&
+		token.Character <= int(position.Character) &&
+		token.Character+token.Length >= int(position.Character)
 }
 
 func (loxService *DocumentService) GetFormattedCode() string {
@@ -143,9 +457,13 @@ func (loxService *DocumentService) GetFormattedCode() string {
 	return formatter.Format(loxService.AST)
 }
 
-func (loxService *DocumentService) GetReferences(position lsp.Position, addDefinition bool) []lsp.Position {
+// GetReferences returns nil once ctx is cancelled
+func (loxService *DocumentService) GetReferences(ctx context.Context, position lsp.Position, addDefinition bool) []lsp.Position {
 	// check if cursor is on a definition
 	for definition := range loxService.SymbolMap {
+		if ctx.Err() != nil {
+			return nil
+		}
 		name, ok := definition.Value.(string)
 		if !ok {
 			continue
@@ -175,12 +493,22 @@ func (loxService *DocumentService) GetReferences(position lsp.Position, addDefin
 	}
 
 	// check if cusor is on a reference
-	definition, found := loxService.GetDefinition(position)
-	if found {
-		return loxService.GetReferences(definition, addDefinition)
+	definitions, found := loxService.GetDefinition(position)
+	if !found {
+		return nil
 	}
-
-	return nil
+	response := make([]lsp.Position, 0)
+	for _, definition := range definitions {
+		if ctx.Err() != nil {
+			return nil
+		}
+		for _, reference := range loxService.GetReferences(ctx, definition, addDefinition) {
+			if !slices.Contains(response, reference) {
+				response = append(response, reference)
+			}
+		}
+	}
+	return response
 
 }
 
@@ -190,6 +518,11 @@ func (loxService *DocumentService) GetSemanticTokens() []uint {
 	var lastToken *lox.Token = &lox.Token{Character: 0, Length: 0, Line: 0}
 
 	for _, token := range loxService.Tokens {
+		// the encoding counts columns and lengths in UTF-16 code units
+		start := loxService.lines.utf16Column(token.Line, token.Character)
+		token.Length = loxService.lines.utf16Column(token.Line, token.Character+token.Length) - start
+		token.Character = start
+
 		switch token.TokenType {
 		case lox.FOR, lox.AND, lox.FUN, lox.VAR, lox.WHILE, lox.IF, lox.ELSE, lox.THIS, lox.SUPER, lox.CLASS, lox.OR, lox.PRINT, lox.RETURN:
 			if token.Line == lastToken.Line {
@@ -243,16 +576,16 @@ func (loxService *DocumentService) GetSemanticTokens() []uint {
 			lastLineToken := token
 			for i, line := range lines {
 				if i == 0 && token.Line == lastToken.Line {
-					response = append(response, 0, uint(token.Character)-uint(lastToken.Character), uint(len(line)), 6, 0)
-					lastLineToken.Length = len(line)
+					response = append(response, 0, uint(token.Character)-uint(lastToken.Character), uint(utf16Length(line)), 6, 0)
+					lastLineToken.Length = utf16Length(line)
 				} else if i == 0 {
-					response = append(response, uint(token.Line)-uint(lastToken.Line), uint(token.Character), uint(len(line)), 6, 0)
-					lastLineToken.Length = len(line)
+					response = append(response, uint(token.Line)-uint(lastToken.Line), uint(token.Character), uint(utf16Length(line)), 6, 0)
+					lastLineToken.Length = utf16Length(line)
 				} else {
-					response = append(response, 1, 0, uint(len(line)), 6, 0)
+					response = append(response, 1, 0, uint(utf16Length(line)), 6, 0)
 					lastLineToken.Line = token.Line + i
 					lastLineToken.Character = token.Character
-					lastLineToken.Length = len(line)
+					lastLineToken.Length = utf16Length(line)
 				}
 
 			}
@@ -0,0 +1,88 @@
+package lsp
+
+import (
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+	"strings"
+	"unicode/utf16"
+)
+
+/* tokens count columns in bytes, the protocol counts them in UTF-16 code units */
+
+// lineIndex converts the byte columns of one text to the UTF-16 columns sent to the client,
+// a nil index leaves columns unchanged
+type lineIndex struct {
+	text   string
+	starts []int // byte offset of the first character of each line
+}
+
+func newLineIndex(text string) *lineIndex {
+	starts := []int{0}
+	for offset := 0; offset < len(text); offset++ {
+		if text[offset] == '\n' {
+			starts = append(starts, offset+1)
+		}
+	}
+	return &lineIndex{text: text, starts: starts}
+}
+
+func (lines *lineIndex) line(line int) string {
+	if lines == nil || line < 0 || line >= len(lines.starts) {
+		return ""
+	}
+	end := len(lines.text)
+	if line+1 < len(lines.starts) {
+		end = lines.starts[line+1] - 1
+	}
+	return lines.text[lines.starts[line]:end]
+}
+
+// utf16Column counts the code units before a byte column, columns past the end of the line keep their distance to it
+func (lines *lineIndex) utf16Column(line int, column int) int {
+	if lines == nil {
+		return column
+	}
+	text := lines.line(line)
+	if column > len(text) {
+		return utf16Length(text) + column - len(text)
+	}
+	return utf16Length(text[:column])
+}
+
+func (lines *lineIndex) position(line int, column int) lsp.Position {
+	return lsp.Position{Line: uint(line), Character: uint(lines.utf16Column(line, column))}
+}
+
+// utf16Position converts a position the server computed in byte columns
+func (lines *lineIndex) utf16Position(position lsp.Position) lsp.Position {
+	return lines.position(int(position.Line), int(position.Character))
+}
+
+func (lines *lineIndex) tokenRange(token lox.Token) lsp.Range {
+	return lsp.Range{
+		Start: lines.position(token.Line, token.Character),
+		End:   lines.position(token.Line, token.Character+token.Length),
+	}
+}
+
+func (lines *lineIndex) spanRange(span lox.Span) lsp.Range {
+	return lsp.Range{
+		Start: lines.position(span.Start.Line, span.Start.Character),
+		End:   lines.position(span.End.Line, span.End.Character),
+	}
+}
+
+func utf16Length(text string) int {
+	length := 0
+	for _, char := range text {
+		length += utf16.RuneLen(char)
+	}
+	return length
+}
+
+// bytePosition converts a position received from the client to the byte columns of the buffer
+func (loxService *DocumentService) bytePosition(position lsp.Position) lsp.Position {
+	offset := offsetAt(loxService.Text, position)
+	lineStart := strings.LastIndexByte(loxService.Text[:offset], '\n') + 1
+	return lsp.Position{Line: position.Line, Character: uint(offset - lineStart)}
+}
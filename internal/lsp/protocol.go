@@ -1,42 +1,74 @@
 package lsp
 
 import (
+	"context"
 	"encoding/json"
-	// "fmt"
+	"fmt"
 	"lox-server/internal/lox"
 	lsp "lox-server/internal/lsp/types"
+	"slices"
+	"time"
 )
 
+func errorResponse(request lsp.JsonRpcRequest, code int, message string) *lsp.JsonRpcResponse {
+	return &lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Error:   &lsp.ResponseError{Code: code, Message: message},
+	}
+}
+
+// invalidParams replaces the result of a request whose params could not be decoded
+func invalidParams(responseObj *lsp.JsonRpcResponse, err error) *lsp.JsonRpcResponse {
+	responseObj.Result = nil
+	responseObj.Error = &lsp.ResponseError{Code: lsp.InvalidParams, Message: fmt.Sprintf("Invalid params: %v", err)}
+	return responseObj
+}
+
+// initializeCheck rejects everything but initialize and exit until the server is initialized
 func initializeCheck(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
-	if !serverState.initialized {
-		response := lsp.JsonRpcResponse{
-			JsonRpc: "2.0",
-			Id:      request.Id,
-			Error:   lsp.InvalidRequest,
-		}
-		return &response
+	if serverState.initialized || request.Method == "initialize" || request.Method == "exit" {
+		return nil
 	}
-	return nil
+	return errorResponse(request, lsp.ServerNotInitialized, "Server not initialized")
 }
 
+// shutdownCheck rejects everything but exit once the server was shut down
 func shutdownCheck(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
-	if !serverState.initialized {
-		response := lsp.JsonRpcResponse{
-			JsonRpc: "2.0",
-			Id:      request.Id,
-			Error:   lsp.InvalidRequest,
-		}
-		return &response
+	if !serverState.shutdown || request.Method == "exit" {
+		return nil
 	}
-	return nil
+	return errorResponse(request, lsp.InvalidRequest, "Server is shutting down")
 }
 
 func protocolInitialize(request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error) {
-	shutCheck := shutdownCheck(request)
-	if shutCheck != nil {
-		serverState.initialized = false
-		return shutCheck, nil
+	if serverState.initialized {
+		return errorResponse(request, lsp.InvalidRequest, "Server is already initialized"), nil
+	}
+
+	var params lsp.InitializeParams
+	if err := getRequestValues(&params, request); err != nil {
+		return errorResponse(request, lsp.InvalidParams, fmt.Sprintf("Invalid params: %v", err)), nil
+	}
+	serverState.initialized = true
+	serverState.capabilities = params.Capabilities
+	// a malformed option keeps the default
+	var options lsp.InitializationOptions
+	if json.Unmarshal(params.InitializationOptions, &options) == nil && options.AnalysisDelay != nil {
+		serverState.analysisDelay = time.Duration(max(*options.AnalysisDelay, 0)) * time.Millisecond
+	}
+	folders := make([]string, 0, len(params.WorkspaceFolders))
+	for _, folder := range params.WorkspaceFolders {
+		folders = append(folders, folder.Uri)
+	}
+	if len(folders) == 0 && params.RootUri != "" {
+		folders = append(folders, params.RootUri)
 	}
+	go (func() {
+		for _, folder := range folders {
+			serverState.workspace.AddFolder(folder)
+		}
+	})()
 
 	responseObj := lsp.JsonRpcResponse{
 		JsonRpc: "2.0",
@@ -45,12 +77,36 @@ func protocolInitialize(request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error
 			"capabilities": map[string]any{
 				"textDocumentSync": map[string]any{
 					"openClose": true,
-					"change":    1,
+					"change":    2,
 				},
 				"definitionProvider":         true,
 				"referencesProvider":         true,
 				"documentFormattingProvider": true,
-				"completionProvider":         map[string]any{},
+				"completionProvider":         map[string]any{"triggerCharacters": []string{"."}},
+				"hoverProvider":              true,
+				"documentSymbolProvider":     true,
+				"workspaceSymbolProvider":    true,
+				"typeHierarchyProvider":      true,
+				"implementationProvider":     true,
+				"callHierarchyProvider":      true,
+				"documentHighlightProvider":  true,
+				"foldingRangeProvider":       true,
+				"selectionRangeProvider":     true,
+				"codeActionProvider": map[string]any{
+					"codeActionKinds": []string{"quickfix"},
+				},
+				"workspace": map[string]any{
+					"workspaceFolders": map[string]any{
+						"supported":           true,
+						"changeNotifications": true,
+					},
+				},
+				"signatureHelpProvider": map[string]any{
+					"triggerCharacters": []string{"(", ","},
+				},
+				"renameProvider": map[string]any{
+					"prepareProvider": true,
+				},
 				"semanticTokensProvider": map[string]any{
 					"legend": lsp.Legend,
 					"range":  false,
@@ -71,12 +127,6 @@ func protocolInitialize(request lsp.JsonRpcRequest) (*lsp.JsonRpcResponse, error
 }
 
 func protocolShutdown(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
-
-	initialCheck := initializeCheck(request)
-	if initialCheck != nil {
-		serverState.shutdown = false
-		return initialCheck
-	}
 	responseObj := lsp.JsonRpcResponse{
 		JsonRpc: "2.0",
 		Id:      request.Id,
@@ -85,7 +135,7 @@ func protocolShutdown(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	return &responseObj
 }
 
-func protocolReferences(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+func protocolReferences(ctx context.Context, request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 
 	responseObj := lsp.JsonRpcResponse{
 		JsonRpc: "2.0",
@@ -103,16 +153,16 @@ func protocolReferences(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	err = json.Unmarshal(requestjson, &requestObj)
 
 	if err != nil {
-		return &responseObj
+		return invalidParams(&responseObj, err)
 	}
 
-	document, ok := serverState.documents[requestObj.TextDocument.Uri]
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
 	if !ok {
 		// serverState.logger.Print(fmt.Sprintf("Get Reference Error: URI %s not found", requestObj.TextDocument.Uri))
 		return &responseObj
 	}
 
-	references := document.GetReferences(requestObj.Position, requestObj.Context.IncludeDeclaration)
+	references := document.GetReferences(ctx, document.bytePosition(requestObj.Position), requestObj.Context.IncludeDeclaration)
 
 	if references == nil {
 		return &responseObj
@@ -123,8 +173,8 @@ func protocolReferences(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 		responseParams = append(responseParams, lsp.Location{
 			Uri: requestObj.TextDocument.Uri,
 			LocRange: lsp.Range{
-				Start: reference,
-				End:   reference,
+				Start: document.lines.utf16Position(reference),
+				End:   document.lines.utf16Position(reference),
 			},
 		})
 	}
@@ -150,10 +200,10 @@ func protocolFormatting(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	err = json.Unmarshal(requestjson, &requestObj)
 
 	if err != nil {
-		return &responseObj
+		return invalidParams(&responseObj, err)
 	}
 
-	document, ok := serverState.documents[requestObj.TextDocument.Uri]
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
 	if !ok {
 		// serverState.logger.Print(fmt.Sprintf("Get Reference Error: URI %s not found", requestObj.TextDocument.Uri))
 		return &responseObj
@@ -168,10 +218,7 @@ func protocolFormatting(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	responseParams = append(responseParams, lsp.TextEdit{
 		Range: lsp.Range{
 			Start: lsp.Position{},
-			End: lsp.Position{
-				Line:      uint(eof.Line),
-				Character: uint(eof.Character),
-			},
+			End:   document.lines.position(eof.Line, eof.Character),
 		},
 		NewText: code,
 	})
@@ -197,15 +244,15 @@ func protocolCompletion(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	err = json.Unmarshal(requestjson, &requestObj)
 
 	if err != nil {
-		return &responseObj
+		return invalidParams(&responseObj, err)
 	}
 
-	document, ok := serverState.documents[requestObj.TextDocument.Uri]
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
 	if !ok {
 		// serverState.logger.Print(fmt.Sprintf("Get Reference Error: URI %s not found", requestObj.TextDocument.Uri))
 		return &responseObj
 	}
-	items := document.GetCompletion(requestObj.Position)
+	items := document.GetCompletion(document.bytePosition(requestObj.Position))
 
 	responseObj.Result = lsp.CompletionList{
 		IsIncomplete: true,
@@ -233,22 +280,278 @@ func protocolDefinition(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	err = json.Unmarshal(requestjson, &requestObj)
 
 	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
 		return &responseObj
 	}
 
-	definition, _ := serverState.documents[requestObj.TextDocument.Uri].GetDefinition(requestObj.Position)
+	definitions, _ := document.GetDefinition(document.bytePosition(requestObj.Position))
 
-	responseObj.Result = lsp.Location{
-		Uri: requestObj.TextDocument.Uri,
-		LocRange: lsp.Range{
-			Start: definition,
-			End:   definition,
+	locations := make([]lsp.Location, 0, len(definitions))
+	for _, definition := range definitions {
+		locations = append(locations, lsp.Location{
+			Uri: requestObj.TextDocument.Uri,
+			LocRange: lsp.Range{
+				Start: document.lines.utf16Position(definition),
+				End:   document.lines.utf16Position(definition),
+			},
+		})
+	}
+	responseObj.Result = locations
+
+	return &responseObj
+}
+
+func protocolHover(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.HoverParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	contents, token, found := document.GetHover(document.bytePosition(requestObj.Position))
+	if !found {
+		return &responseObj
+	}
+
+	responseObj.Result = lsp.Hover{
+		Contents: lsp.MarkupContent{
+			Kind:  "markdown",
+			Value: contents,
 		},
+		Range: document.lines.tokenRange(token),
+	}
+
+	return &responseObj
+}
+
+func protocolPrepareRename(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.PrepareRenameParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	token, err := document.PrepareRename(document.bytePosition(requestObj.Position))
+	if err != nil {
+		responseObj.Error = &lsp.ResponseError{Code: lsp.RequestFailed, Message: err.Error()}
+		return &responseObj
+	}
+
+	name, _ := token.Value.(string)
+	responseObj.Result = lsp.PrepareRenameResult{
+		Range:       document.lines.tokenRange(token),
+		Placeholder: name,
 	}
 
 	return &responseObj
 }
 
+func protocolRename(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.RenameParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	if !lox.IsIdentifier(requestObj.NewName) {
+		responseObj.Error = &lsp.ResponseError{
+			Code:    lsp.InvalidParams,
+			Message: fmt.Sprintf("%s is not a valid identifier", requestObj.NewName),
+		}
+		return &responseObj
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	edits, err := document.Rename(document.bytePosition(requestObj.Position), requestObj.NewName)
+	if err != nil {
+		responseObj.Error = &lsp.ResponseError{Code: lsp.RequestFailed, Message: err.Error()}
+		return &responseObj
+	}
+
+	responseObj.Result = lsp.WorkspaceEdit{
+		Changes: map[string][]lsp.TextEdit{
+			requestObj.TextDocument.Uri: edits,
+		},
+	}
+
+	return &responseObj
+}
+
+func protocolDocumentSymbol(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.DocumentSymbolParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	responseObj.Result = document.GetDocumentSymbols()
+	return &responseObj
+}
+
+func protocolWorkspaceSymbol(ctx context.Context, request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.WorkspaceSymbolParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	responseObj.Result = serverState.workspace.Symbols(ctx, requestObj.Query)
+	return &responseObj
+}
+
+func protocolCodeAction(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.CodeActionParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	if len(requestObj.Context.Only) > 0 && !slices.Contains(requestObj.Context.Only, "quickfix") {
+		responseObj.Result = []lsp.CodeAction{}
+		return &responseObj
+	}
+
+	responseObj.Result = document.GetCodeActions(requestObj.Context.Diagnostics)
+	return &responseObj
+}
+
+func protocolSignatureHelp(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.SignatureHelpParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	if signatureHelp := document.GetSignatureHelp(document.bytePosition(requestObj.Position)); signatureHelp != nil {
+		responseObj.Result = *signatureHelp
+	}
+	return &responseObj
+}
+
 func protocolSemanticTokens(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	responseObj := lsp.JsonRpcResponse{
 		JsonRpc: "2.0",
@@ -266,10 +569,15 @@ func protocolSemanticTokens(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	err = json.Unmarshal(requestjson, &requestObj)
 
 	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
 		return &responseObj
 	}
 
-	semanticTokens := serverState.documents[requestObj.TextDocument.Uri].GetSemanticTokens()
+	semanticTokens := document.GetSemanticTokens()
 
 	responseObj.Result = lsp.SemanticTokens{
 		Data: semanticTokens,
@@ -279,24 +587,22 @@ func protocolSemanticTokens(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
 	return &responseObj
 }
 
-func diagnosticNotification(parseErrors []lox.CompileError, uri string, version int) lsp.JsonRpcNotification {
+func diagnosticNotification(parseErrors []lox.CompileError, lines *lineIndex, uri string, version int) lsp.JsonRpcNotification {
 
 	diagnostic := []lsp.Diagnostic{}
 	for _, e := range parseErrors {
-		diagnostic = append(diagnostic, lsp.Diagnostic{
+		entry := lsp.Diagnostic{
 			Severity: e.Severity,
 			Message:  e.Message,
 			ErrRange: lsp.Range{
-				Start: lsp.Position{
-					Line:      uint(e.Line),
-					Character: uint(e.Char),
-				},
-				End: lsp.Position{
-					Line:      uint(e.Line),
-					Character: uint(e.Char),
-				},
+				Start: lines.position(e.Line, e.Char),
+				End:   lines.position(e.EndLine, e.EndChar),
 			},
-		})
+		}
+		if e.Code != "" {
+			entry.Code = e.Code
+		}
+		diagnostic = append(diagnostic, entry)
 	}
 
 	result := lsp.PublishDiagnosticParams{Uri: uri, Version: version, Diagnostics: diagnostic}
@@ -310,28 +616,298 @@ func diagnosticNotification(parseErrors []lox.CompileError, uri string, version
 	return responseObj
 }
 
-func register(id int) lsp.JsonRpcRequest {
-	requestObj := lsp.JsonRpcRequest{
-		JsonRpc: "2.0",
-		Id:      id,
-		Method:  "client/registerCapability",
-		Params: lsp.RegistrationParams{
-			Registrations: []lsp.Registration{{
-				Id:     "definition",
-				Method: "textDocument/definition",
-				RegisterOptions: lsp.DefinitionRegistrationOptions{
-					TextDocumentRegistrationOptions: lsp.TextDocumentRegistrationOptions{
-						DocumentSelector: []lsp.DocumentFilter{
-							{
-								Pattern: "**/*.lox",
-							},
-						},
+func registerFileWatcher() lsp.RegistrationParams {
+	return lsp.RegistrationParams{
+		Registrations: []lsp.Registration{{
+			Id:     "watchedFiles",
+			Method: "workspace/didChangeWatchedFiles",
+			RegisterOptions: lsp.DidChangeWatchedFilesRegistrationOptions{
+				Watchers: []lsp.FileSystemWatcher{
+					{
+						GlobPattern: "**/*.lox",
 					},
 				},
-			}},
-		},
+			},
+		}},
+	}
+}
+
+func protocolPrepareTypeHierarchy(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.TypeHierarchyPrepareParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	responseObj.Result = document.PrepareTypeHierarchy(document.bytePosition(requestObj.Position))
+	return &responseObj
+}
+
+func protocolTypeHierarchySupertypes(ctx context.Context, request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.TypeHierarchySupertypesParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	responseObj.Result = TypeHierarchySupertypes(ctx, requestObj.Item)
+	return &responseObj
+}
+
+func protocolTypeHierarchySubtypes(ctx context.Context, request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.TypeHierarchySubtypesParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	responseObj.Result = TypeHierarchySubtypes(ctx, requestObj.Item)
+	return &responseObj
+}
+
+func protocolImplementation(ctx context.Context, request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.ImplementationParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	responseObj.Result = document.GetImplementations(ctx, document.bytePosition(requestObj.Position))
+	return &responseObj
+}
+
+func protocolPrepareCallHierarchy(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.CallHierarchyPrepareParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	responseObj.Result = document.PrepareCallHierarchy(document.bytePosition(requestObj.Position))
+	return &responseObj
+}
+
+func protocolIncomingCalls(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.CallHierarchyIncomingCallsParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.Item.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	responseObj.Result = document.IncomingCalls(requestObj.Item)
+	return &responseObj
+}
+
+func protocolOutgoingCalls(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.CallHierarchyOutgoingCallsParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.Item.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	responseObj.Result = document.OutgoingCalls(requestObj.Item)
+	return &responseObj
+}
+
+func protocolDocumentHighlight(ctx context.Context, request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.DocumentHighlightParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	responseObj.Result = document.GetDocumentHighlights(ctx, document.bytePosition(requestObj.Position))
+	return &responseObj
+}
+
+func protocolFoldingRange(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.FoldingRangeParams
+
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
 	}
 
-	return requestObj
+	responseObj.Result = document.GetFoldingRanges(serverState.capabilities.TextDocument.FoldingRange.LineFoldingOnly)
+	return &responseObj
+}
+
+func protocolSelectionRange(request lsp.JsonRpcRequest) *lsp.JsonRpcResponse {
+	responseObj := lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Result:  nil,
+	}
+
+	requestjson, err := json.Marshal(request.Params)
+	var requestObj lsp.SelectionRangeParams
 
+	if err != nil {
+		return &responseObj
+	}
+
+	err = json.Unmarshal(requestjson, &requestObj)
+
+	if err != nil {
+		return invalidParams(&responseObj, err)
+	}
+
+	document, ok := serverState.documents.Snapshot(requestObj.TextDocument.Uri)
+	if !ok {
+		return &responseObj
+	}
+
+	responseObj.Result = document.GetSelectionRanges(requestObj.Positions)
+	return &responseObj
 }
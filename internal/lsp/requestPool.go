@@ -0,0 +1,107 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	lsp "lox-server/internal/lsp/types"
+	"sync"
+)
+
+/* requests run on a pool of workers, notifications and lifecycle requests stay on the reading loop so they apply in order */
+
+const requestWorkers = 4
+
+// queued requests beyond this block the reading loop until a worker frees up
+const requestQueueSize = 256
+
+type requestPool struct {
+	jobs     chan pooledRequest
+	mutex    sync.Mutex
+	inFlight map[any]context.CancelFunc // ids decode as float64 or string, both are comparable
+}
+
+type pooledRequest struct {
+	ctx     context.Context
+	request lsp.JsonRpcRequest
+}
+
+func (pool *requestPool) start(workers int) {
+	pool.jobs = make(chan pooledRequest, requestQueueSize)
+	pool.inFlight = make(map[any]context.CancelFunc)
+	for range workers {
+		go pool.work()
+	}
+}
+
+// runsConcurrently is false for notifications and for requests that change the server lifecycle
+func runsConcurrently(request lsp.JsonRpcRequest) bool {
+	if request.Id == nil {
+		return false
+	}
+	switch request.Method {
+	case "initialize", "shutdown", "exit":
+		return false
+	}
+	return true
+}
+
+func (pool *requestPool) submit(request lsp.JsonRpcRequest) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.mutex.Lock()
+	pool.inFlight[request.Id] = cancel
+	pool.mutex.Unlock()
+	pool.jobs <- pooledRequest{ctx: ctx, request: request}
+}
+
+// cancel is a no-op for requests that already finished or were never seen
+func (pool *requestPool) cancel(id any) {
+	defer pool.mutex.Unlock()
+	pool.mutex.Lock()
+	if cancel, ok := pool.inFlight[id]; ok {
+		cancel()
+	}
+}
+
+func (pool *requestPool) work() {
+	for job := range pool.jobs {
+		response := pool.run(job)
+
+		pool.mutex.Lock()
+		if cancel, ok := pool.inFlight[job.request.Id]; ok {
+			cancel()
+			delete(pool.inFlight, job.request.Id)
+		}
+		pool.mutex.Unlock()
+
+		if response != nil {
+			writeMessage(response)
+		}
+	}
+}
+
+// run skips requests cancelled while queued and replaces the result of those cancelled while running
+func (pool *requestPool) run(job pooledRequest) []byte {
+	if job.ctx.Err() != nil {
+		return cancelledResponse(job.request)
+	}
+	response, err := respond(job.ctx, job.request)
+	if err != nil {
+		return nil
+	}
+	if job.ctx.Err() != nil {
+		return cancelledResponse(job.request)
+	}
+	return response
+}
+
+func cancelledResponse(request lsp.JsonRpcRequest) []byte {
+	response, err := json.Marshal(lsp.JsonRpcResponse{
+		JsonRpc: "2.0",
+		Id:      request.Id,
+		Error:   &lsp.ResponseError{Code: lsp.RequestCancelled, Message: "Request cancelled"},
+	})
+	if err != nil {
+		return nil
+	}
+	return response
+}
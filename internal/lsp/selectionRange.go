@@ -0,0 +1,58 @@
+package lsp
+
+import (
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+	"strings"
+)
+
+/* selection ranges growing from the token at the cursor through the enclosing nodes to the whole file */
+
+func (loxService *DocumentService) GetSelectionRanges(positions []lsp.Position) []lsp.SelectionRange {
+	selections := make([]lsp.SelectionRange, 0, len(positions))
+	for _, position := range positions {
+		position := loxService.bytePosition(position)
+		cursor := lox.Position{Line: int(position.Line), Character: int(position.Character)}
+
+		// the walk is pre-order, so enclosing nodes come before the nodes they contain
+		ranges := []lsp.Range{loxService.fileRange()}
+		lox.Inspect(loxService.AST, func(node lox.Node) bool {
+			span := node.GetSpan()
+			if span.IsZero() || !span.Contains(cursor) {
+				return true
+			}
+			if spanRange := loxService.lines.spanRange(span); spanRange != ranges[len(ranges)-1] {
+				ranges = append(ranges, spanRange)
+			}
+			return true
+		})
+		for _, token := range loxService.Tokens {
+			if token.TokenType == lox.NEWLINE || token.TokenType == lox.EOF || !tokenAtCursor(token, position) {
+				continue
+			}
+			if tokenRange := loxService.lines.tokenRange(token); tokenRange != ranges[len(ranges)-1] && rangeContains(ranges[len(ranges)-1], tokenRange) {
+				ranges = append(ranges, tokenRange)
+			}
+			break
+		}
+
+		var selection *lsp.SelectionRange
+		for _, selectionRange := range ranges {
+			selection = &lsp.SelectionRange{Range: selectionRange, Parent: selection}
+		}
+		selections = append(selections, *selection)
+	}
+	return selections
+}
+
+func (loxService *DocumentService) fileRange() lsp.Range {
+	lines := strings.Split(loxService.Text, "\n")
+	return lsp.Range{
+		Start: lsp.Position{Line: 0, Character: 0},
+		End:   lsp.Position{Line: uint(len(lines) - 1), Character: uint(utf16Length(lines[len(lines)-1]))},
+	}
+}
+
+func rangeContains(outer lsp.Range, inner lsp.Range) bool {
+	return !positionBefore(inner.Start, outer.Start) && !positionBefore(outer.End, inner.End)
+}
@@ -5,9 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	lsp "lox-server/internal/lsp/types"
 	"os"
 	"sync"
-	// "time"
+	"time"
 )
 
 var serverState struct {
@@ -15,19 +16,24 @@ var serverState struct {
 	initialized bool
 	writer      *os.File
 	//logger           *log.Logger
-	loggerMu         sync.Mutex
-	idCount          int
-	serverRequestIds map[int]bool
-	documents        map[string]*DocumentService
+	loggerMu       sync.Mutex
+	clientRequests pendingRequests
+	documents      DocumentStore
+	workspace      WorkspaceIndex
+	capabilities   lsp.ClientCapabilities
+	analysisDelay  time.Duration
+	requests       requestPool
 }
 
 func initializeServerState() {
 	serverState.initialized = false
 	serverState.shutdown = false
 	serverState.writer = os.Stdout
-	serverState.idCount = 1
-	serverState.serverRequestIds = make(map[int]bool)
-	serverState.documents = make(map[string]*DocumentService)
+	serverState.clientRequests.Initialize()
+	serverState.documents.Initialize()
+	serverState.analysisDelay = defaultAnalysisDelay
+	serverState.requests.start(requestWorkers)
+	serverState.workspace.Initialize()
 }
 
 func StartServer() {
@@ -70,26 +76,30 @@ func sendNotification(response []byte) {
 
 }
 
-func sendRequest(method string) {
-	id := serverState.idCount
-	serverState.idCount++
-	serverState.serverRequestIds[id] = true
-	switch method {
-	case "client/registerCapability":
-		requestObj := register(id)
+// sendRequest writes a request to the client, callback runs with its answer, an error or a timeout
+// and may be nil when the answer does not matter
+func sendRequest(method string, params any, callback clientCallback) {
+	id := serverState.clientRequests.add(callback, clientRequestTimeout)
 
-		request, err := json.Marshal(requestObj)
-		if err != nil {
-			// serverState.logger.Print(fmt.Sprintf("invalid Request: %v\n", err))
-			return
-		}
-		if err := writeMessage(request); err != nil {
-			// serverState.logger.Print(fmt.Sprintf("Error writing request: %v\n", err))
-		}
-		// serverState.logger.Print(string(request))
+	requestObj := lsp.JsonRpcRequest{
+		JsonRpc: "2.0",
+		Id:      id,
+		Method:  method,
+		Params:  params,
 	}
-
+	request, err := json.Marshal(requestObj)
+	if err != nil {
+		// serverState.logger.Print(fmt.Sprintf("invalid Request: %v\n", err))
+		serverState.clientRequests.finish(id, nil, err)
+		return
+	}
+	if err := writeMessage(request); err != nil {
+		// serverState.logger.Print(fmt.Sprintf("Error writing request: %v\n", err))
+		serverState.clientRequests.finish(id, nil, err)
+	}
+	// serverState.logger.Print(string(request))
 }
+
 func getLogger(fileName string) *log.Logger {
 	logfile, err := os.OpenFile(fileName, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
 	if err != nil {
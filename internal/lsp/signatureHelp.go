@@ -0,0 +1,202 @@
+package lsp
+
+import (
+	"fmt"
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+	"slices"
+	"strings"
+)
+
+/* parameter hints for the call surrounding the cursor */
+
+var nativeSignatures map[string][]string = map[string][]string{
+	"clock": {},
+}
+
+func (loxService *DocumentService) GetSignatureHelp(position lsp.Position) *lsp.SignatureHelp {
+	// the parsed tokens can lag behind the buffer while typing, so scan the current text
+	var scanner lox.Scanner
+	tokens, _, err := scanner.Scan(loxService.Text)
+	if err != nil {
+		return nil
+	}
+
+	paren, activeParameter, found := enclosingCall(tokens, position)
+	if !found || paren < 1 || tokens[paren-1].TokenType != lox.IDENTIFIER {
+		return nil
+	}
+	if paren >= 2 && tokens[paren-2].TokenType == lox.FUN { // parameter list of a declaration
+		return nil
+	}
+	callee := tokens[paren-1]
+	name, _ := callee.Value.(string)
+
+	declarations := lox.Declarations(loxService.AST)
+	signatures := make([]lsp.SignatureInformation, 0)
+
+	switch {
+	case paren >= 3 && tokens[paren-2].TokenType == lox.DOT:
+		var classes []*lox.Declaration
+		switch object := tokens[paren-3]; {
+		case object.TokenType == lox.THIS:
+			classes = append(classes, enclosingClass(declarations, position))
+		case object.TokenType == lox.SUPER:
+			if class := enclosingClass(declarations, position); class != nil {
+				if parent := class.Node.(*lox.ClassDecl).Parent; parent != nil {
+					parentName, _ := parent.Value.(string)
+					classes = append(classes, lox.FindClass(declarations, parentName))
+				}
+			}
+		default:
+			// the type of an arbitrary object is unknown, offer every class with such a method
+			for _, declaration := range declarations {
+				if declaration.Kind == lox.DECLARATION_CLASS {
+					classes = append(classes, declaration)
+				}
+			}
+		}
+		for _, class := range classes {
+			if class == nil {
+				continue
+			}
+			method := lox.FindMethod(declarations, class, name)
+			if method == nil || (len(classes) > 1 && method.Parent != class) {
+				continue
+			}
+			className, _ := method.Parent.Name.Value.(string)
+			signatures = append(signatures, functionSignature(className+"."+name, method))
+		}
+
+	case slices.Contains(lox.NativeFunctions, name):
+		signatures = append(signatures, signatureInformation(fmt.Sprintf("fun %s", name), nativeSignatures[name], "native function"))
+
+	default:
+		declaration := loxService.calleeDeclaration(declarations, callee)
+		if declaration == nil {
+			return nil
+		}
+		switch declaration.Kind {
+		case lox.DECLARATION_FUNCTION:
+			signatures = append(signatures, functionSignature("fun "+name, declaration))
+		case lox.DECLARATION_CLASS:
+			if initializer := lox.FindMethod(declarations, declaration, "init"); initializer != nil {
+				signatures = append(signatures, functionSignature(name, initializer))
+			} else {
+				signatures = append(signatures, signatureInformation(name, []string{}, declaration.Documentation()))
+			}
+		}
+	}
+
+	if len(signatures) == 0 {
+		return nil
+	}
+	return &lsp.SignatureHelp{
+		Signatures:      signatures,
+		ActiveSignature: 0,
+		ActiveParameter: uint(activeParameter),
+	}
+}
+
+// enclosingCall finds the unclosed '(' before the cursor and counts the arguments already written
+func enclosingCall(tokens []lox.Token, position lsp.Position) (int, int, bool) {
+	depth, commas := 0, 0
+	for i := len(tokens) - 1; i >= 0; i-- {
+		token := tokens[i]
+		beforeCursor := token.Line < int(position.Line) ||
+			(token.Line == int(position.Line) && token.Character < int(position.Character))
+		if !beforeCursor {
+			continue
+		}
+
+		switch token.TokenType {
+		case lox.PARANRIGHT:
+			depth++
+		case lox.PARANLEFT:
+			if depth == 0 {
+				return i, commas, true
+			}
+			depth--
+		case lox.COMMA:
+			if depth == 0 {
+				commas++
+			}
+		case lox.SEMICOLON, lox.BRACELEFT, lox.BRACERIGHT:
+			if depth == 0 {
+				return 0, 0, false
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// calleeDeclaration resolves the callee through the parsed references, falling back to its name
+func (loxService *DocumentService) calleeDeclaration(declarations []*lox.Declaration, callee lox.Token) *lox.Declaration {
+	definition, _, found := loxService.getSymbol(lsp.Position{Line: uint(callee.Line), Character: uint(callee.Character)})
+	name, _ := callee.Value.(string)
+
+	var byName *lox.Declaration
+	for _, declaration := range declarations {
+		if found && declaration.Name == definition {
+			return declaration
+		}
+		declarationName, _ := declaration.Name.Value.(string)
+		isCallable := declaration.Kind == lox.DECLARATION_FUNCTION || declaration.Kind == lox.DECLARATION_CLASS
+		if isCallable && declarationName == name && byName == nil {
+			byName = declaration
+		}
+	}
+	return byName
+}
+
+func enclosingClass(declarations []*lox.Declaration, position lsp.Position) *lox.Declaration {
+	var enclosing *lox.Declaration
+	for _, declaration := range declarations {
+		if declaration.Kind != lox.DECLARATION_CLASS {
+			continue
+		}
+		// the position is in byte columns, so the range is too
+		classRange := declarationRange(nil, declaration)
+		if positionBefore(position, classRange.Start) || positionBefore(classRange.End, position) {
+			continue
+		}
+		enclosing = declaration // nested classes come later in source order
+	}
+	return enclosing
+}
+
+func functionSignature(label string, function *lox.Declaration) lsp.SignatureInformation {
+	parameters := make([]string, 0)
+	for _, parameter := range function.Node.(*lox.FuncDecl).Parameters {
+		name, ok := parameter.(*lox.Variable).Identifier.Value.(string)
+		if ok {
+			parameters = append(parameters, name)
+		}
+	}
+	return signatureInformation(label, parameters, function.Documentation())
+}
+
+func signatureInformation(label string, parameters []string, documentation string) lsp.SignatureInformation {
+	var signature strings.Builder
+	signature.WriteString(label + "(")
+	information := make([]lsp.ParameterInformation, 0, len(parameters))
+	// label offsets count UTF-16 code units like every other column
+	length := utf16Length(signature.String())
+	for i, parameter := range parameters {
+		if i != 0 {
+			signature.WriteString(", ")
+			length += 2
+		}
+		start := uint(length)
+		signature.WriteString(parameter)
+		length += utf16Length(parameter)
+		information = append(information, lsp.ParameterInformation{Label: [2]uint{start, uint(length)}})
+	}
+	signature.WriteString(")")
+
+	return lsp.SignatureInformation{
+		Label:         signature.String(),
+		Documentation: documentation,
+		Parameters:    information,
+	}
+}
@@ -0,0 +1,187 @@
+package lsp
+
+import (
+	"context"
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+	"maps"
+	"slices"
+)
+
+/* class hierarchy across the workspace, lox has no imports so superclasses are matched by name */
+
+type workspaceClass struct {
+	uri         string
+	declaration *lox.Declaration
+}
+
+func (loxService *DocumentService) PrepareTypeHierarchy(position lsp.Position) []lsp.TypeHierarchyItem {
+	definition, _, found := loxService.getSymbol(position)
+	if !found {
+		return nil
+	}
+	files := serverState.workspace.snapshot()
+	for _, declaration := range files[loxService.Uri].declarations {
+		if declaration.Kind == lox.DECLARATION_CLASS && declaration.Name == definition {
+			return []lsp.TypeHierarchyItem{typeHierarchyItem(files, workspaceClass{uri: loxService.Uri, declaration: declaration})}
+		}
+	}
+	return nil
+}
+
+func TypeHierarchySupertypes(ctx context.Context, item lsp.TypeHierarchyItem) []lsp.TypeHierarchyItem {
+	files := serverState.workspace.snapshot()
+	class, found := findItemClass(files, item)
+	if !found {
+		return nil
+	}
+	items := make([]lsp.TypeHierarchyItem, 0)
+	if superclass, found := newClassHierarchy(ctx, files).superclasses[class]; found {
+		items = append(items, typeHierarchyItem(files, superclass))
+	}
+	return items
+}
+
+func TypeHierarchySubtypes(ctx context.Context, item lsp.TypeHierarchyItem) []lsp.TypeHierarchyItem {
+	files := serverState.workspace.snapshot()
+	class, found := findItemClass(files, item)
+	if !found {
+		return nil
+	}
+	items := make([]lsp.TypeHierarchyItem, 0)
+	for _, subclass := range newClassHierarchy(ctx, files).subclasses[class] {
+		items = append(items, typeHierarchyItem(files, subclass))
+	}
+	return items
+}
+
+// GetImplementations lists the methods overriding the method at the cursor in every subclass, nil once ctx is cancelled
+func (loxService *DocumentService) GetImplementations(ctx context.Context, position lsp.Position) []lsp.Location {
+	var definitions []lsp.Position
+	if definition, _, found := loxService.getSymbol(position); found {
+		definitions = tokenPositions([]lox.Token{definition})
+	} else if positions, found := loxService.GetDefinition(position); found {
+		definitions = positions
+	}
+
+	files := serverState.workspace.snapshot()
+	hierarchy := newClassHierarchy(ctx, files)
+	locations := make([]lsp.Location, 0)
+	for _, declaration := range files[loxService.Uri].declarations {
+		isDefinition := slices.Contains(definitions, tokenStart(declaration.Name))
+		if declaration.Kind != lox.DECLARATION_METHOD || !isDefinition {
+			continue
+		}
+		name, _ := declaration.Name.Value.(string)
+
+		// walk every class below the one declaring the method
+		queue := []workspaceClass{{uri: loxService.Uri, declaration: declaration.Parent}}
+		visited := map[*lox.Declaration]bool{declaration.Parent: true}
+		for len(queue) > 0 {
+			if ctx.Err() != nil {
+				return nil
+			}
+			class := queue[0]
+			queue = queue[1:]
+			for _, subclass := range hierarchy.subclasses[class] {
+				if visited[subclass.declaration] {
+					continue
+				}
+				visited[subclass.declaration] = true
+				queue = append(queue, subclass)
+
+				for _, member := range lox.ClassMembers(files[subclass.uri].declarations, subclass.declaration) {
+					if member.Kind == lox.MEMBER_METHOD && member.Name.Value == name {
+						locations = append(locations, lsp.Location{Uri: subclass.uri, LocRange: files[subclass.uri].lines.tokenRange(member.Name)})
+					}
+				}
+			}
+		}
+	}
+	return locations
+}
+
+func typeHierarchyItem(files map[string]workspaceFile, class workspaceClass) lsp.TypeHierarchyItem {
+	name, _ := class.declaration.Name.Value.(string)
+	lines := files[class.uri].lines
+	return lsp.TypeHierarchyItem{
+		Name:           name,
+		Kind:           lsp.SymbolKindClass,
+		Detail:         class.declaration.Signature(),
+		Uri:            class.uri,
+		Range:          declarationRange(lines, class.declaration),
+		SelectionRange: lines.tokenRange(class.declaration.Name),
+	}
+}
+
+// findItemClass finds the class an item was created for by the position of its name
+func findItemClass(files map[string]workspaceFile, item lsp.TypeHierarchyItem) (workspaceClass, bool) {
+	for _, declaration := range files[item.Uri].declarations {
+		if declaration.Kind == lox.DECLARATION_CLASS && files[item.Uri].lines.tokenRange(declaration.Name) == item.SelectionRange {
+			return workspaceClass{uri: item.Uri, declaration: declaration}, true
+		}
+	}
+	return workspaceClass{}, false
+}
+
+// classHierarchy links the classes of a workspace snapshot, it is built once per request
+type classHierarchy struct {
+	superclasses map[workspaceClass]workspaceClass
+	subclasses   map[workspaceClass][]workspaceClass // sorted by uri and line
+}
+
+// newClassHierarchy prefers a superclass in the same file and otherwise takes the first file declaring the name,
+// once ctx is cancelled it stops early and the hierarchy is incomplete
+func newClassHierarchy(ctx context.Context, files map[string]workspaceFile) classHierarchy {
+	uris := slices.Sorted(maps.Keys(files))
+
+	fileClasses := make(map[string]map[string]*lox.Declaration, len(files))
+	firstClasses := make(map[string]workspaceClass)
+	for _, uri := range uris {
+		if ctx.Err() != nil {
+			break
+		}
+		classes := make(map[string]*lox.Declaration)
+		for _, declaration := range files[uri].declarations {
+			name, ok := declaration.Name.Value.(string)
+			if declaration.Kind != lox.DECLARATION_CLASS || !ok {
+				continue
+			}
+			if _, found := classes[name]; !found {
+				classes[name] = lox.FindClass(files[uri].declarations, name)
+			}
+			if _, found := firstClasses[name]; !found {
+				firstClasses[name] = workspaceClass{uri: uri, declaration: classes[name]}
+			}
+		}
+		fileClasses[uri] = classes
+	}
+
+	hierarchy := classHierarchy{
+		superclasses: make(map[workspaceClass]workspaceClass),
+		subclasses:   make(map[workspaceClass][]workspaceClass),
+	}
+	// files are walked in order and declarations in source order, so subclasses come out sorted
+	for _, uri := range uris {
+		if ctx.Err() != nil {
+			break
+		}
+		for _, declaration := range files[uri].declarations {
+			if declaration.Kind != lox.DECLARATION_CLASS || declaration.Node.(*lox.ClassDecl).Parent == nil {
+				continue
+			}
+			name, _ := declaration.Node.(*lox.ClassDecl).Parent.Value.(string)
+			superclass, found := firstClasses[name]
+			if sameFile := fileClasses[uri][name]; sameFile != nil {
+				superclass, found = workspaceClass{uri: uri, declaration: sameFile}, true
+			}
+			if !found {
+				continue
+			}
+			class := workspaceClass{uri: uri, declaration: declaration}
+			hierarchy.superclasses[class] = superclass
+			hierarchy.subclasses[superclass] = append(hierarchy.subclasses[superclass], class)
+		}
+	}
+	return hierarchy
+}
@@ -0,0 +1,7 @@
+package lsp
+
+// CompletionItemKind values used for completion items
+const (
+	CompletionItemKindMethod = 2
+	CompletionItemKindField  = 5
+)
@@ -0,0 +1,8 @@
+package lsp
+
+// DocumentHighlightKind values used for document highlights
+const (
+	DocumentHighlightKindText  = 1
+	DocumentHighlightKindRead  = 2
+	DocumentHighlightKindWrite = 3
+)
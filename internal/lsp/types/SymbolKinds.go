@@ -0,0 +1,13 @@
+package lsp
+
+// SymbolKind values used for document and workspace symbols
+const (
+	SymbolKindFile        = 1
+	SymbolKindClass       = 5
+	SymbolKindMethod      = 6
+	SymbolKindProperty    = 7
+	SymbolKindField       = 8
+	SymbolKindConstructor = 9
+	SymbolKindFunction    = 12
+	SymbolKindVariable    = 13
+)
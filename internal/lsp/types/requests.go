@@ -1,5 +1,7 @@
 package lsp
 
+import "encoding/json"
+
 type TextDocumentIdentifier struct {
 	Uri string `json:"uri"`
 }
@@ -16,12 +18,51 @@ type TextDocumentItem struct {
 	Text       string `json:"text"`
 }
 
+// a nil range replaces the whole document
 type TextDocumentContentChangeEvent struct {
-	TextRange   Range  `json:"range"`
+	TextRange   *Range `json:"range"`
 	RangeLength *uint  `json:"rangeLength"`
 	Text        string `json:"text"`
 }
 
+type WorkspaceFolder struct {
+	Uri  string `json:"uri"`
+	Name string `json:"name"`
+}
+
+type DynamicRegistrationCapability struct {
+	DynamicRegistration bool `json:"dynamicRegistration"`
+}
+
+type WorkspaceClientCapabilities struct {
+	DidChangeWatchedFiles DynamicRegistrationCapability `json:"didChangeWatchedFiles"`
+}
+
+type FoldingRangeClientCapabilities struct {
+	LineFoldingOnly bool `json:"lineFoldingOnly"`
+}
+
+type TextDocumentClientCapabilities struct {
+	FoldingRange FoldingRangeClientCapabilities `json:"foldingRange"`
+}
+
+type ClientCapabilities struct {
+	Workspace    WorkspaceClientCapabilities    `json:"workspace"`
+	TextDocument TextDocumentClientCapabilities `json:"textDocument"`
+}
+
+// InitializationOptions are the server specific settings a client sends with initialize
+type InitializationOptions struct {
+	AnalysisDelay *int `json:"analysisDelay"` // milliseconds a document has to stay unchanged before it is analysed
+}
+
+type InitializeParams struct {
+	RootUri               string             `json:"rootUri"`
+	WorkspaceFolders      []WorkspaceFolder  `json:"workspaceFolders"`
+	Capabilities          ClientCapabilities `json:"capabilities"`
+	InitializationOptions json.RawMessage    `json:"initializationOptions"` // decoded on its own, bad settings must not fail initialize
+}
+
 type DidOpenTextDocumentParams struct {
 	TextDocument TextDocumentItem `json:"textDocument"`
 }
@@ -35,6 +76,33 @@ type DidChangeTextDocumentParams struct {
 	ContentChanges []TextDocumentContentChangeEvent `json:"contentChanges"`
 }
 
+type WorkspaceFoldersChangeEvent struct {
+	Added   []WorkspaceFolder `json:"added"`
+	Removed []WorkspaceFolder `json:"removed"`
+}
+
+type DidChangeWorkspaceFoldersParams struct {
+	Event WorkspaceFoldersChangeEvent `json:"event"`
+}
+
+/*
+1 = Created
+2 = Changed
+3 = Deleted
+*/
+type FileEvent struct {
+	Uri  string `json:"uri"`
+	Type int    `json:"type"`
+}
+
+type DidChangeWatchedFilesParams struct {
+	Changes []FileEvent `json:"changes"`
+}
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
 type RegistrationParams struct {
 	Registrations []Registration `json:"registrations"`
 }
@@ -61,6 +129,79 @@ type CompletionParams struct {
 	TextDocumentPositionParams `json:",inline"`
 }
 
+type HoverParams struct {
+	TextDocumentPositionParams `json:",inline"`
+}
+
+type PrepareRenameParams struct {
+	TextDocumentPositionParams `json:",inline"`
+}
+
+type RenameParams struct {
+	TextDocumentPositionParams `json:",inline"`
+	NewName                    string `json:"newName"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type CodeActionContext struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Only        []string     `json:"only"`
+}
+
+type CodeActionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	CodeRange    Range                  `json:"range"`
+	Context      CodeActionContext      `json:"context"`
+}
+
+type SignatureHelpParams struct {
+	TextDocumentPositionParams `json:",inline"`
+}
+
+type TypeHierarchyPrepareParams struct {
+	TextDocumentPositionParams `json:",inline"`
+}
+
+type TypeHierarchySupertypesParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+type TypeHierarchySubtypesParams struct {
+	Item TypeHierarchyItem `json:"item"`
+}
+
+type ImplementationParams struct {
+	TextDocumentPositionParams `json:",inline"`
+}
+
+type CallHierarchyPrepareParams struct {
+	TextDocumentPositionParams `json:",inline"`
+}
+
+type CallHierarchyIncomingCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+type CallHierarchyOutgoingCallsParams struct {
+	Item CallHierarchyItem `json:"item"`
+}
+
+type DocumentHighlightParams struct {
+	TextDocumentPositionParams `json:",inline"`
+}
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type SelectionRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Positions    []Position             `json:"positions"`
+}
+
 type SemanticTokensParams struct {
 	TextDocument TextDocumentIdentifier `json:"textDocument"`
 }
@@ -98,6 +239,14 @@ type DefinitionRegistrationOptions struct {
 	TextDocumentRegistrationOptions `json:",inline"`
 }
 
+type FileSystemWatcher struct {
+	GlobPattern string `json:"globPattern"`
+}
+
+type DidChangeWatchedFilesRegistrationOptions struct {
+	Watchers []FileSystemWatcher `json:"watchers"`
+}
+
 type JsonRpcNotification struct {
 	JsonRpc string `json:"jsonrpc"`
 	Method  any    `json:"method"`
@@ -108,6 +257,10 @@ type Id interface {
 	string | int
 }
 
+type CancelParams struct {
+	Id any `json:"id"`
+}
+
 type JsonRpcRequest struct {
 	JsonRpc string `json:"jsonrpc"`
 	Id      any    `json:"id"`
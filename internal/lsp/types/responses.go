@@ -1,5 +1,10 @@
 package lsp
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type Diagnostic struct {
 	/*
 	   1 = Error
@@ -10,6 +15,7 @@ type Diagnostic struct {
 	Severity int    `json:"severity"`
 	ErrRange Range  `json:"range"`
 	Message  string `json:"message"`
+	Code     any    `json:"code,omitempty"` // integer or string, codes of other sources come back in code action requests
 }
 
 type PublishDiagnosticParams struct {
@@ -19,10 +25,44 @@ type PublishDiagnosticParams struct {
 }
 
 type JsonRpcResponse struct {
-	JsonRpc string `json:"jsonrpc"`
-	Id      any    `json:"id"`
-	Result  any    `json:"result"`
-	Error   any    `json:"error"`
+	JsonRpc string         `json:"jsonrpc"`
+	Id      any            `json:"id"`
+	Result  any            `json:"result"`
+	Error   *ResponseError `json:"error,omitempty"`
+}
+
+// MarshalJSON writes either the result or the error, a successful response keeps a null result
+func (response JsonRpcResponse) MarshalJSON() ([]byte, error) {
+	if response.Error != nil {
+		return json.Marshal(struct {
+			JsonRpc string         `json:"jsonrpc"`
+			Id      any            `json:"id"`
+			Error   *ResponseError `json:"error"`
+		}{JsonRpc: response.JsonRpc, Id: response.Id, Error: response.Error})
+	}
+	return json.Marshal(struct {
+		JsonRpc string `json:"jsonrpc"`
+		Id      any    `json:"id"`
+		Result  any    `json:"result"`
+	}{JsonRpc: response.JsonRpc, Id: response.Id, Result: response.Result})
+}
+
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (err *ResponseError) Error() string {
+	return fmt.Sprintf("%s (%d)", err.Message, err.Code)
+}
+
+// ClientResponse is the answer of the client to a request sent by the server
+type ClientResponse struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      any             `json:"id"`
+	Result  json.RawMessage `json:"result"`
+	Error   *ResponseError  `json:"error"`
 }
 
 type Location struct {
@@ -35,13 +75,64 @@ type TextEdit struct {
 	NewText string `json:"newText"`
 }
 
+type WorkspaceEdit struct {
+	Changes map[string][]TextEdit `json:"changes"`
+}
+
+type CodeAction struct {
+	Title       string        `json:"title"`
+	Kind        string        `json:"kind"`
+	Diagnostics []Diagnostic  `json:"diagnostics"`
+	Edit        WorkspaceEdit `json:"edit"`
+	IsPreferred bool          `json:"isPreferred"`
+}
+
+type PrepareRenameResult struct {
+	Range       Range  `json:"range"`
+	Placeholder string `json:"placeholder"`
+}
+
+type DocumentSymbol struct {
+	Name           string           `json:"name"`
+	Detail         string           `json:"detail"`
+	Kind           int              `json:"kind"`
+	Range          Range            `json:"range"`
+	SelectionRange Range            `json:"selectionRange"`
+	Children       []DocumentSymbol `json:"children"`
+}
+
+type SymbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      Location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+type ParameterInformation struct {
+	Label [2]uint `json:"label"` // start and end offset in the signature label
+}
+
+type SignatureInformation struct {
+	Label         string                 `json:"label"`
+	Documentation string                 `json:"documentation,omitempty"`
+	Parameters    []ParameterInformation `json:"parameters"`
+}
+
+type SignatureHelp struct {
+	Signatures      []SignatureInformation `json:"signatures"`
+	ActiveSignature uint                   `json:"activeSignature"`
+	ActiveParameter uint                   `json:"activeParameter"`
+}
+
 type CompletionItemLabelDetails struct {
 	Detail      string `json:"detail"`
 	Description string `json:"description"`
 }
 
 type CompletionItem struct {
-	Label string `json:"label"`
+	Label  string `json:"label"`
+	Kind   int    `json:"kind,omitempty"`
+	Detail string `json:"detail,omitempty"`
 }
 
 type CompletionList struct {
@@ -52,3 +143,60 @@ type CompletionList struct {
 type SemanticTokens struct {
 	Data []uint `json:"data"`
 }
+
+type MarkupContent struct {
+	Kind  string `json:"kind"` // plaintext | markdown
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	Contents MarkupContent `json:"contents"`
+	Range    Range         `json:"range"`
+}
+
+type TypeHierarchyItem struct {
+	Name           string `json:"name"`
+	Kind           int    `json:"kind"`
+	Detail         string `json:"detail,omitempty"`
+	Uri            string `json:"uri"`
+	Range          Range  `json:"range"`
+	SelectionRange Range  `json:"selectionRange"`
+	Data           any    `json:"data,omitempty"`
+}
+
+type CallHierarchyItem struct {
+	Name           string `json:"name"`
+	Kind           int    `json:"kind"`
+	Detail         string `json:"detail,omitempty"`
+	Uri            string `json:"uri"`
+	Range          Range  `json:"range"`
+	SelectionRange Range  `json:"selectionRange"`
+}
+
+type CallHierarchyIncomingCall struct {
+	From       CallHierarchyItem `json:"from"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+type CallHierarchyOutgoingCall struct {
+	To         CallHierarchyItem `json:"to"`
+	FromRanges []Range           `json:"fromRanges"`
+}
+
+type DocumentHighlight struct {
+	Range Range `json:"range"`
+	Kind  int   `json:"kind"`
+}
+
+type FoldingRange struct {
+	StartLine      uint   `json:"startLine"`
+	StartCharacter *uint  `json:"startCharacter,omitempty"`
+	EndLine        uint   `json:"endLine"`
+	EndCharacter   *uint  `json:"endCharacter,omitempty"`
+	Kind           string `json:"kind,omitempty"`
+}
+
+type SelectionRange struct {
+	Range  Range           `json:"range"`
+	Parent *SelectionRange `json:"parent,omitempty"`
+}
@@ -0,0 +1,289 @@
+package lsp
+
+import (
+	"context"
+	"io/fs"
+	"lox-server/internal/lox"
+	lsp "lox-server/internal/lsp/types"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+/* index of the declarations in every lox file under the workspace folders */
+
+type workspaceFile struct {
+	uri          string // as the client spelled it, results point back to this form
+	declarations []*lox.Declaration
+	lines        *lineIndex
+	open         bool // open buffers take priority over the file on disk
+}
+
+type WorkspaceIndex struct {
+	folders map[string]bool
+	files   map[string]*workspaceFile // keyed by normalized uri, clients encode the same path differently
+	mutex   sync.Mutex
+}
+
+func (index *WorkspaceIndex) Initialize() {
+	index.folders = make(map[string]bool)
+	index.files = make(map[string]*workspaceFile)
+}
+
+func (index *WorkspaceIndex) AddFolder(uri string) {
+	root, err := uriToPath(uri)
+	if err != nil {
+		return
+	}
+	index.mutex.Lock()
+	index.folders[root] = true
+	index.mutex.Unlock()
+
+	filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			if path != root && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Ext(path) == ".lox" {
+			index.IndexFile(pathToUri(path))
+		}
+		return nil
+	})
+}
+
+func (index *WorkspaceIndex) RemoveFolder(uri string) {
+	root, err := uriToPath(uri)
+	if err != nil {
+		return
+	}
+	defer index.mutex.Unlock()
+	index.mutex.Lock()
+
+	delete(index.folders, root)
+	for key, file := range index.files {
+		path, err := uriToPath(file.uri)
+		if err != nil || file.open || index.inWorkspace(path) {
+			continue
+		}
+		delete(index.files, key)
+	}
+}
+
+// IndexFile parses a file from disk unless it is open in the editor
+func (index *WorkspaceIndex) IndexFile(uri string) {
+	key := normalizeUri(uri)
+	index.mutex.Lock()
+	file, ok := index.files[key]
+	index.mutex.Unlock()
+	if ok && file.open {
+		return
+	}
+
+	path, err := uriToPath(uri)
+	if err != nil {
+		return
+	}
+	code, err := os.ReadFile(path)
+	if err != nil {
+		index.Remove(uri)
+		return
+	}
+	_, ast, _, _, _, _, err := lox.ParseCode(string(code))
+	if err != nil {
+		return
+	}
+
+	defer index.mutex.Unlock()
+	index.mutex.Lock()
+	// the file may have been opened while it was parsed
+	if file, ok := index.files[key]; ok && file.open {
+		return
+	}
+	index.files[key] = &workspaceFile{uri: uri, declarations: lox.Declarations(ast), lines: newLineIndex(string(code))}
+}
+
+// UpdateOpen replaces the indexed declarations with those of an open buffer
+func (index *WorkspaceIndex) UpdateOpen(uri string, ast []lox.Node, lines *lineIndex) {
+	declarations := lox.Declarations(ast)
+	defer index.mutex.Unlock()
+	index.mutex.Lock()
+	index.files[normalizeUri(uri)] = &workspaceFile{uri: uri, declarations: declarations, lines: lines, open: true}
+}
+
+// Close falls back to the file on disk once the buffer is closed
+func (index *WorkspaceIndex) Close(uri string) {
+	index.mutex.Lock()
+	if file, ok := index.files[normalizeUri(uri)]; ok {
+		file.open = false
+	}
+	path, err := uriToPath(uri)
+	inWorkspace := err == nil && index.inWorkspace(path)
+	index.mutex.Unlock()
+
+	if inWorkspace {
+		index.IndexFile(uri)
+	} else {
+		index.Remove(uri)
+	}
+}
+
+func (index *WorkspaceIndex) Remove(uri string) {
+	defer index.mutex.Unlock()
+	key := normalizeUri(uri)
+	index.mutex.Lock()
+	if file, ok := index.files[key]; ok && file.open {
+		return
+	}
+	delete(index.files, key)
+}
+
+// Symbols returns nil once ctx is cancelled
+func (index *WorkspaceIndex) Symbols(ctx context.Context, query string) []lsp.SymbolInformation {
+	type match struct {
+		symbol lsp.SymbolInformation
+		score  int
+	}
+	matches := make([]match, 0)
+
+	index.mutex.Lock()
+	for _, file := range index.files {
+		if ctx.Err() != nil {
+			index.mutex.Unlock()
+			return nil
+		}
+		for _, declaration := range file.declarations {
+			isGlobal := declaration.Kind == lox.DECLARATION_VARIABLE && declaration.Parent == nil
+			if declaration.Kind == lox.DECLARATION_PARAMETER || (declaration.Kind == lox.DECLARATION_VARIABLE && !isGlobal) {
+				continue
+			}
+			name, ok := declaration.Name.Value.(string)
+			if !ok {
+				continue
+			}
+			score, found := fuzzyScore(query, name)
+			if !found {
+				continue
+			}
+
+			symbol := lsp.SymbolInformation{
+				Name:     name,
+				Kind:     symbolKind(declaration),
+				Location: lsp.Location{Uri: file.uri, LocRange: file.lines.tokenRange(declaration.Name)},
+			}
+			if declaration.Parent != nil {
+				symbol.ContainerName, _ = declaration.Parent.Name.Value.(string)
+			}
+			matches = append(matches, match{symbol: symbol, score: score})
+		}
+	}
+	index.mutex.Unlock()
+
+	slices.SortFunc(matches, func(a match, b match) int {
+		if a.score != b.score {
+			return b.score - a.score
+		}
+		if len(a.symbol.Name) != len(b.symbol.Name) {
+			return len(a.symbol.Name) - len(b.symbol.Name)
+		}
+		return strings.Compare(a.symbol.Name, b.symbol.Name)
+	})
+
+	symbols := make([]lsp.SymbolInformation, 0, len(matches))
+	for _, match := range matches {
+		symbols = append(symbols, match.symbol)
+	}
+	return symbols
+}
+
+// snapshot copies every indexed file by the uri the client knows it under,
+// declarations and line indexes are never modified in place
+func (index *WorkspaceIndex) snapshot() map[string]workspaceFile {
+	defer index.mutex.Unlock()
+	index.mutex.Lock()
+	files := make(map[string]workspaceFile, len(index.files))
+	for _, file := range index.files {
+		files[file.uri] = *file
+	}
+	return files
+}
+
+func (index *WorkspaceIndex) inWorkspace(path string) bool {
+	for folder := range index.folders {
+		relative, err := filepath.Rel(folder, path)
+		if err == nil && !strings.HasPrefix(relative, "..") {
+			return true
+		}
+	}
+	return false
+}
+
+// fuzzyScore matches the query as a case insensitive subsequence of name,
+// prefix and consecutive matches score higher
+func fuzzyScore(query string, name string) (int, bool) {
+	queryRunes := []rune(strings.ToLower(query))
+	nameRunes := []rune(strings.ToLower(name))
+	score, matched, last := 0, 0, -2
+
+	for i := 0; i < len(nameRunes) && matched < len(queryRunes); i++ {
+		if nameRunes[i] != queryRunes[matched] {
+			continue
+		}
+		switch {
+		case i == 0:
+			score += 3
+		case i == last+1:
+			score += 2
+		default:
+			score += 1
+		}
+		last = i
+		matched++
+	}
+	return score, matched == len(queryRunes)
+}
+
+func uriToPath(uri string) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	path := parsed.Path
+	// file:///c:/dir carries the drive letter after a slash
+	if len(path) > 1 && filepath.VolumeName(path[1:]) != "" {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path), nil
+}
+
+func pathToUri(path string) string {
+	slashed := filepath.ToSlash(path)
+	if filepath.VolumeName(path) != "" {
+		slashed = "/" + slashed
+	}
+	uri := url.URL{Scheme: "file", Path: slashed}
+	return uri.String()
+}
+
+// normalizeUri gives file uris one spelling, so file:///c%3A/a.lox and file:///C:/a.lox are the same file
+func normalizeUri(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return uri
+	}
+	path, err := uriToPath(uri)
+	if err != nil {
+		return uri
+	}
+	if volume := filepath.VolumeName(path); len(volume) == 2 {
+		path = strings.ToLower(volume) + path[2:]
+	}
+	return pathToUri(path)
+}